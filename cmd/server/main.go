@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,7 +14,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/github-mcp-http/internal/auth"
+	"github.com/github-mcp-http/internal/githubauth"
 	httpserver "github.com/github-mcp-http/internal/transport/http"
+	"github.com/github-mcp-http/internal/transport/stdio"
+	"github.com/github-mcp-http/pkg/sse"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -21,16 +27,35 @@ var (
 	cfgFile string
 	rootCmd = &cobra.Command{
 		Use:   "github-mcp-http",
-		Short: "GitHub MCP server with HTTP/SSE transport",
-		Long:  "A Model Context Protocol server for GitHub operations using HTTP and Server-Sent Events",
+		Short: "GitHub MCP server with HTTP/SSE and stdio transports",
+		Long:  "A Model Context Protocol server for GitHub operations using HTTP and Server-Sent Events, or stdio",
 	}
 )
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.github-mcp-http.yaml)")
+	rootCmd.PersistentFlags().String("github-token", "", "GitHub Personal Access Token")
+	rootCmd.PersistentFlags().Bool("read-only", false, "Enable read-only mode")
+	rootCmd.PersistentFlags().Int("max-pages", 10, "Maximum GitHub pages fetched by one auto-paginated call")
+	rootCmd.PersistentFlags().Duration("poll-interval", time.Minute, "How often subscribed resources are checked for changes")
+	rootCmd.PersistentFlags().String("token-secret", "", "Secret that signs client bearer tokens (at least 32 bytes)")
+	rootCmd.PersistentFlags().Int64("github-app-id", 0, "ID of a GitHub App to act as instead of a personal token")
+	rootCmd.PersistentFlags().String("github-app-private-key", "", "Private key of the GitHub App: a PEM file path, or the PEM itself")
+	rootCmd.PersistentFlags().String("github-app-owner", "", "Account whose GitHub App installation serves requests not tied to an owner, such as GraphQL queries")
+
+	viper.BindPFlag("github.token", rootCmd.PersistentFlags().Lookup("github-token"))
+	viper.BindPFlag("github.read_only", rootCmd.PersistentFlags().Lookup("read-only"))
+	viper.BindPFlag("github.max_pages", rootCmd.PersistentFlags().Lookup("max-pages"))
+	viper.BindPFlag("github.poll_interval", rootCmd.PersistentFlags().Lookup("poll-interval"))
+	viper.BindPFlag("auth.token_secret", rootCmd.PersistentFlags().Lookup("token-secret"))
+	viper.BindPFlag("github.app_id", rootCmd.PersistentFlags().Lookup("github-app-id"))
+	viper.BindPFlag("github.app_private_key", rootCmd.PersistentFlags().Lookup("github-app-private-key"))
+	viper.BindPFlag("github.app_owner", rootCmd.PersistentFlags().Lookup("github-app-owner"))
 	
 	rootCmd.AddCommand(httpCmd)
+	rootCmd.AddCommand(stdioCmd)
+	rootCmd.AddCommand(authCmd)
 }
 
 var httpCmd = &cobra.Command{
@@ -42,22 +67,113 @@ var httpCmd = &cobra.Command{
 func init() {
 	httpCmd.Flags().String("host", "0.0.0.0", "Host to bind to")
 	httpCmd.Flags().Int("port", 8080, "Port to listen on")
-	httpCmd.Flags().String("github-token", "", "GitHub Personal Access Token")
-	httpCmd.Flags().Bool("read-only", false, "Enable read-only mode")
 	httpCmd.Flags().String("tls-cert", "", "Path to TLS certificate")
 	httpCmd.Flags().String("tls-key", "", "Path to TLS key")
+	httpCmd.Flags().Int("sse-buffer-size", sse.DefaultBufferSize, "Events queued per SSE client before the slow client policy applies")
+	httpCmd.Flags().Int("sse-replay-size", sse.DefaultReplaySize, "Recent events kept per session for Last-Event-ID replay")
+	httpCmd.Flags().String("sse-slow-client-policy", "disconnect", "What to do when an SSE client falls behind: disconnect, drop-oldest or block")
+	httpCmd.Flags().Duration("sse-write-timeout", sse.DefaultWriteTimeout, "Maximum time for one write to an SSE client")
+	httpCmd.Flags().Bool("session-bind-ip", false, "Reject session IDs used from an address other than the one that created the session")
+	httpCmd.Flags().Bool("session-bind-user-agent", false, "Reject session IDs used with a User-Agent other than the one that created the session")
+	httpCmd.Flags().Bool("trust-proxy", false, "Take the client address from X-Forwarded-For")
+	httpCmd.Flags().Int("trusted-proxies", 1, "Number of proxies in front of the server that append to X-Forwarded-For")
+	httpCmd.Flags().StringSlice("api-keys", nil, "Client API keys as name:sha256hex entries (see auth api-key)")
+	httpCmd.Flags().Bool("server-token-fallback", false, "Let sessions without their own X-GitHub-Token act with the server's GitHub token")
+	httpCmd.Flags().String("public-url", "", "Base URL clients reach the server at, required for OAuth")
+	httpCmd.Flags().Bool("oauth", false, "Serve a local OAuth authorization server where users sign in with their API key")
+	httpCmd.Flags().String("oauth-issuer", "", "Issuer URL of an external OAuth authorization server")
+	httpCmd.Flags().String("oauth-introspection-url", "", "Token introspection endpoint of the external authorization server")
+	httpCmd.Flags().String("oauth-client-id", "", "Client ID the server introspects tokens with")
+	httpCmd.Flags().String("oauth-client-secret", "", "Client secret the server introspects tokens with")
+	httpCmd.Flags().String("github-client-id", "", "Client ID of the GitHub OAuth app users sign in to GitHub with")
+	httpCmd.Flags().String("github-client-secret", "", "Client secret of the GitHub OAuth app, required for browser sign in")
+	httpCmd.Flags().StringSlice("github-scopes", []string{"repo", "read:org"}, "GitHub scopes requested when users sign in")
+	httpCmd.Flags().String("github-token-store", "", "File that keeps users' GitHub tokens across restarts")
+	httpCmd.Flags().String("github-token-key", "", "Secret that encrypts stored GitHub tokens")
 	
 	viper.BindPFlag("host", httpCmd.Flags().Lookup("host"))
 	viper.BindPFlag("port", httpCmd.Flags().Lookup("port"))
-	viper.BindPFlag("github.token", httpCmd.Flags().Lookup("github-token"))
-	viper.BindPFlag("github.read_only", httpCmd.Flags().Lookup("read-only"))
 	viper.BindPFlag("tls.cert", httpCmd.Flags().Lookup("tls-cert"))
 	viper.BindPFlag("tls.key", httpCmd.Flags().Lookup("tls-key"))
+	viper.BindPFlag("sse.buffer_size", httpCmd.Flags().Lookup("sse-buffer-size"))
+	viper.BindPFlag("sse.replay_size", httpCmd.Flags().Lookup("sse-replay-size"))
+	viper.BindPFlag("sse.slow_client_policy", httpCmd.Flags().Lookup("sse-slow-client-policy"))
+	viper.BindPFlag("sse.write_timeout", httpCmd.Flags().Lookup("sse-write-timeout"))
+	viper.BindPFlag("session.bind_ip", httpCmd.Flags().Lookup("session-bind-ip"))
+	viper.BindPFlag("session.bind_user_agent", httpCmd.Flags().Lookup("session-bind-user-agent"))
+	viper.BindPFlag("trust_proxy", httpCmd.Flags().Lookup("trust-proxy"))
+	viper.BindPFlag("trusted_proxies", httpCmd.Flags().Lookup("trusted-proxies"))
+	viper.BindPFlag("auth.api_keys", httpCmd.Flags().Lookup("api-keys"))
+	viper.BindPFlag("github.server_token_fallback", httpCmd.Flags().Lookup("server-token-fallback"))
+	viper.BindPFlag("public_url", httpCmd.Flags().Lookup("public-url"))
+	viper.BindPFlag("oauth.enabled", httpCmd.Flags().Lookup("oauth"))
+	viper.BindPFlag("oauth.issuer", httpCmd.Flags().Lookup("oauth-issuer"))
+	viper.BindPFlag("oauth.introspection_url", httpCmd.Flags().Lookup("oauth-introspection-url"))
+	viper.BindPFlag("oauth.client_id", httpCmd.Flags().Lookup("oauth-client-id"))
+	viper.BindPFlag("oauth.client_secret", httpCmd.Flags().Lookup("oauth-client-secret"))
+	viper.BindPFlag("github.client_id", httpCmd.Flags().Lookup("github-client-id"))
+	viper.BindPFlag("github.client_secret", httpCmd.Flags().Lookup("github-client-secret"))
+	viper.BindPFlag("github.scopes", httpCmd.Flags().Lookup("github-scopes"))
+	viper.BindPFlag("github.token_store", httpCmd.Flags().Lookup("github-token-store"))
+	viper.BindPFlag("github.token_key", httpCmd.Flags().Lookup("github-token-key"))
 }
 
-func runHTTPServer(cmd *cobra.Command, args []string) {
-	// Get GitHub token from environment variables first, then fallback to viper
-	// Priority: GITHUB_TOKEN > GITHUB_MCP_GITHUB_TOKEN > viper config
+var stdioCmd = &cobra.Command{
+	Use:   "stdio",
+	Short: "Serve MCP over stdin/stdout",
+	Run:   runStdioServer,
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage credentials for HTTP clients",
+}
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "api-key NAME",
+	Short: "Generate an API key and the config entry that accepts it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, entry, err := auth.GenerateAPIKey(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("API key (give this to the client): %s\n", key)
+		fmt.Printf("Config entry (add to --api-keys):  %s\n", entry)
+		return nil
+	},
+}
+
+var tokenCmd = &cobra.Command{
+	Use:   "token SUBJECT",
+	Short: "Issue a bearer token signed with the token secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tokens, err := auth.NewTokens(viper.GetString("auth.token_secret"))
+		if err != nil {
+			return err
+		}
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		token, err := tokens.Issue(args[0], ttl)
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+		return nil
+	},
+}
+
+func init() {
+	tokenCmd.Flags().Duration("ttl", 24*time.Hour, "How long the token is valid")
+
+	authCmd.AddCommand(apiKeyCmd)
+	authCmd.AddCommand(tokenCmd)
+}
+
+// resolveGitHubToken reads the GitHub token from environment variables first,
+// then falls back to viper.
+// Priority: GITHUB_TOKEN > GITHUB_MCP_GITHUB_TOKEN > viper config
+func resolveGitHubToken() string {
 	githubToken := os.Getenv("GITHUB_TOKEN")
 	if githubToken == "" {
 		githubToken = os.Getenv("GITHUB_MCP_GITHUB_TOKEN")
@@ -65,6 +181,191 @@ func runHTTPServer(cmd *cobra.Command, args []string) {
 	if githubToken == "" {
 		githubToken = viper.GetString("github.token")
 	}
+	return githubToken
+}
+
+// loadGitHubApp returns the configured GitHub App, or nil if there is none.
+func loadGitHubApp() (*githubauth.App, error) {
+	appID := viper.GetInt64("github.app_id")
+	if appID == 0 {
+		return nil, nil
+	}
+
+	// The key may be given inline, which suits platforms without files.
+	privateKey := []byte(viper.GetString("github.app_private_key"))
+	if len(privateKey) == 0 {
+		return nil, fmt.Errorf("--github-app-private-key is required with --github-app-id")
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(privateKey)), "-----BEGIN") {
+		var err error
+		if privateKey, err = os.ReadFile(string(privateKey)); err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	}
+
+	return githubauth.NewApp(githubauth.AppConfig{
+		ID:           appID,
+		PrivateKey:   privateKey,
+		DefaultOwner: viper.GetString("github.app_owner"),
+	})
+}
+
+// configureAuth sets up client authentication from the configured token
+// secret, API keys and OAuth settings. The authenticator stays nil if none
+// are configured.
+func configureAuth(config *httpserver.ServerConfig) error {
+	var chain auth.Chain
+
+	if secret := viper.GetString("auth.token_secret"); secret != "" {
+		tokens, err := auth.NewTokens(secret)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, tokens)
+	}
+
+	var keys *auth.APIKeys
+	if entries := viper.GetStringSlice("auth.api_keys"); len(entries) > 0 {
+		var err error
+		if keys, err = auth.ParseAPIKeys(entries); err != nil {
+			return err
+		}
+		// Local credentials are checked first, so that an API key sent as
+		// a bearer token is never passed on to an introspection endpoint.
+		chain = append(chain, keys)
+	}
+
+	localOAuth, issuer := viper.GetBool("oauth.enabled"), viper.GetString("oauth.issuer")
+	if localOAuth || issuer != "" {
+		if localOAuth && issuer != "" {
+			return fmt.Errorf("--oauth and --oauth-issuer are mutually exclusive")
+		}
+		if viper.GetString("public_url") == "" {
+			return fmt.Errorf("--public-url is required for OAuth")
+		}
+		publicURL, err := auth.ParsePublicURL(viper.GetString("public_url"))
+		if err != nil {
+			return err
+		}
+
+		if localOAuth {
+			if keys == nil {
+				return fmt.Errorf("--oauth signs users in with API keys, so --api-keys is required")
+			}
+			config.ProtectedResource = auth.NewProtectedResource(publicURL, publicURL)
+			config.AuthorizationServer = auth.NewAuthorizationServer(config.ProtectedResource, keys)
+			chain = append(chain, config.AuthorizationServer)
+		} else {
+			introspectionURL := viper.GetString("oauth.introspection_url")
+			if introspectionURL == "" {
+				return fmt.Errorf("--oauth-introspection-url is required with --oauth-issuer")
+			}
+			config.ProtectedResource = auth.NewProtectedResource(publicURL, issuer)
+			chain = append(chain, auth.NewIntrospection(introspectionURL,
+				viper.GetString("oauth.client_id"), viper.GetString("oauth.client_secret"), config.ProtectedResource))
+		}
+	}
+
+	if len(chain) > 0 {
+		config.Authenticator = chain
+	}
+	return nil
+}
+
+// configureGitHubLogin lets clients sign in to GitHub when a GitHub OAuth app
+// is configured. Tokens are kept per client, so it needs client
+// authentication.
+func configureGitHubLogin(config *httpserver.ServerConfig) error {
+	clientID := viper.GetString("github.client_id")
+	if clientID == "" {
+		return nil
+	}
+	if config.Authenticator == nil {
+		return fmt.Errorf("--github-client-id requires client authentication")
+	}
+
+	key, path := viper.GetString("github.token_key"), viper.GetString("github.token_store")
+	if key == "" {
+		if path != "" {
+			return fmt.Errorf("--github-token-key is required with --github-token-store")
+		}
+		// Tokens kept in memory only need a key for the life of the process.
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		key = hex.EncodeToString(b)
+	}
+	store, err := githubauth.NewStore(key, path)
+	if err != nil {
+		return err
+	}
+
+	loginConfig := githubauth.Config{
+		ClientID:     clientID,
+		ClientSecret: viper.GetString("github.client_secret"),
+		Scopes:       viper.GetStringSlice("github.scopes"),
+	}
+	// The web flow's pages are served by the server, so it needs the
+	// public URL.
+	if raw := viper.GetString("public_url"); raw != "" && loginConfig.ClientSecret != "" {
+		publicURL, err := auth.ParsePublicURL(raw)
+		if err != nil {
+			return err
+		}
+		loginConfig.PublicURL = publicURL
+	}
+
+	config.GitHubLogin = githubauth.NewLogin(loginConfig, store)
+	return nil
+}
+
+func resolveReadOnly() bool {
+	readOnly := viper.GetBool("github.read_only")
+	if envReadOnly := os.Getenv("GITHUB_MCP_GITHUB_READ_ONLY"); envReadOnly != "" {
+		readOnly = envReadOnly == "true"
+	}
+	return readOnly
+}
+
+// reloadOnHangup re-reads the config file on SIGHUP and applies the
+// read-only setting to the running server.
+func reloadOnHangup(setReadOnly func(bool)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			if err := viper.ReadInConfig(); err != nil {
+				log.Printf("Failed to reload config: %v", err)
+				continue
+			}
+			readOnly := resolveReadOnly()
+			log.Printf("Reloaded config, read-only: %t", readOnly)
+			setReadOnly(readOnly)
+		}
+	}()
+}
+
+func runHTTPServer(cmd *cobra.Command, args []string) {
+	// Each session acts as the GitHub user whose token it connects with;
+	// the server's token is shared only when explicitly allowed.
+	githubToken := resolveGitHubToken()
+	if !viper.GetBool("github.server_token_fallback") {
+		if githubToken != "" {
+			log.Println("Server GitHub token is not shared with sessions; enable --server-token-fallback to share it")
+		}
+		githubToken = ""
+	}
+	// A GitHub App is an organisation's credential rather than a person's,
+	// so configuring one is enough to share it.
+	app, err := loadGitHubApp()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if app != nil {
+		log.Println("Sessions without their own GitHub token act as the GitHub App")
+	}
 
 	// Get other config from environment variables with fallback to viper
 	host := os.Getenv("GITHUB_MCP_HOST")
@@ -84,24 +385,50 @@ func runHTTPServer(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	readOnly := viper.GetBool("github.read_only")
-	if envReadOnly := os.Getenv("GITHUB_MCP_GITHUB_READ_ONLY"); envReadOnly != "" {
-		readOnly = envReadOnly == "true"
+	readOnly := resolveReadOnly()
+
+	policy, err := sse.ParsePolicy(viper.GetString("sse.slow_client_policy"))
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
 	config := &httpserver.ServerConfig{
-		Host:        host,
-		Port:        port,
-		TLSCert:     viper.GetString("tls.cert"),
-		TLSKey:      viper.GetString("tls.key"),
-		GitHubToken: githubToken,
-		ReadOnly:    readOnly,
+		Host:         host,
+		Port:         port,
+		TLSCert:      viper.GetString("tls.cert"),
+		TLSKey:       viper.GetString("tls.key"),
+		GitHubToken:  githubToken,
+		GitHubApp:    app,
+		ReadOnly:     readOnly,
+		MaxPages:     viper.GetInt("github.max_pages"),
+		PollInterval: viper.GetDuration("github.poll_interval"),
+		SSE: sse.Options{
+			BufferSize:   viper.GetInt("sse.buffer_size"),
+			ReplaySize:   viper.GetInt("sse.replay_size"),
+			Policy:       policy,
+			WriteTimeout: viper.GetDuration("sse.write_timeout"),
+		},
+		BindSessionIP:        viper.GetBool("session.bind_ip"),
+		BindSessionUserAgent: viper.GetBool("session.bind_user_agent"),
+		TrustProxy:           viper.GetBool("trust_proxy"),
+		TrustedProxies:       viper.GetInt("trusted_proxies"),
+	}
+
+	if err := configureAuth(config); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := configureGitHubLogin(config); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if config.Authenticator == nil {
+		log.Println("WARNING: no client authentication configured, so anyone who can reach the server can use it")
 	}
 
 	server, err := httpserver.NewServer(config)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
+	reloadOnHangup(server.SetReadOnly)
 
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Host, config.Port),
@@ -140,6 +467,42 @@ func runHTTPServer(cmd *cobra.Command, args []string) {
 	log.Println("Server stopped")
 }
 
+func runStdioServer(cmd *cobra.Command, args []string) {
+	// stdout is reserved for protocol messages.
+	log.SetOutput(os.Stderr)
+
+	// The single stdio session always acts with the server's credentials.
+	githubToken := resolveGitHubToken()
+	app, err := loadGitHubApp()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if githubToken == "" && app == nil {
+		log.Fatalf("GitHub token or GitHub App is required")
+	}
+
+	config := &stdio.ServerConfig{
+		GitHubToken:  githubToken,
+		GitHubApp:    app,
+		ReadOnly:     resolveReadOnly(),
+		MaxPages:     viper.GetInt("github.max_pages"),
+		PollInterval: viper.GetDuration("github.poll_interval"),
+	}
+
+	server, err := stdio.NewServer(config)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+	reloadOnHangup(server.SetReadOnly)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
 func initConfig() {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -157,7 +520,7 @@ func initConfig() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
 
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries an API key. Keys are also accepted as bearer tokens
+// for clients that can only set Authorization.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix marks generated keys so they are recognizable in leaks.
+const apiKeyPrefix = "ghmcp_"
+
+// APIKeys authenticates static API keys. Only SHA-256 hashes of the keys
+// are configured, so the configuration does not hold usable credentials;
+// keys are long random strings, which makes a fast hash sufficient.
+type APIKeys struct {
+	// names maps the hex SHA-256 of each key to the name it was issued to.
+	names map[string]string
+}
+
+// ParseAPIKeys parses "name:sha256hex" entries, as printed by
+// GenerateAPIKey.
+func ParseAPIKeys(entries []string) (*APIKeys, error) {
+	keys := &APIKeys{names: make(map[string]string, len(entries))}
+	for _, entry := range entries {
+		name, hash, ok := strings.Cut(entry, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("api key entry %q: want name:sha256hex", entry)
+		}
+		hash = strings.ToLower(hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key entry %q: hash is not a hex SHA-256", entry)
+		}
+		keys.names[hash] = name
+	}
+	return keys, nil
+}
+
+// Len returns the number of configured keys.
+func (k *APIKeys) Len() int {
+	return len(k.names)
+}
+
+func (k *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		var ok bool
+		if key, ok = bearerToken(r); !ok {
+			return nil, ErrNoCredentials
+		}
+	}
+
+	principal, ok := k.lookup(key)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+func (k *APIKeys) lookup(key string) (*Principal, bool) {
+	// The lookup is keyed by the hash, so its timing reveals nothing about
+	// the configured keys.
+	name, ok := k.names[HashAPIKey(key)]
+	if !ok {
+		return nil, false
+	}
+	return &Principal{Subject: name, Method: "api_key"}, true
+}
+
+func (k *APIKeys) Challenge() string {
+	return `ApiKey realm="github-mcp-http"`
+}
+
+// HashAPIKey returns the hex SHA-256 of key, as configured for it.
+func HashAPIKey(key string) string {
+	return hashSecret(key)
+}
+
+// GenerateAPIKey returns a new random API key and its configuration entry
+// for name.
+func GenerateAPIKey(name string) (key, entry string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, name + ":" + HashAPIKey(key), nil
+}
@@ -0,0 +1,105 @@
+// Package auth authenticates the clients of the HTTP transport.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials
+	// the authenticator understands.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for credentials that are malformed,
+	// unknown or forged.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrExpired is returned for credentials that were valid once.
+	ErrExpired = errors.New("credentials expired")
+)
+
+// Principal is an authenticated client.
+type Principal struct {
+	// Subject names the client, e.g. the name an API key was issued to.
+	Subject string `json:"subject"`
+	// Method is how the client authenticated, e.g. "api_key".
+	Method string `json:"method"`
+	// ExpiresAt is when the credentials expire, or zero if they do not.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// String identifies the principal across authentication methods.
+func (p *Principal) String() string {
+	return p.Method + ":" + p.Subject
+}
+
+// Authenticator identifies the client that sent a request.
+type Authenticator interface {
+	// Authenticate returns the principal behind r, or ErrNoCredentials if
+	// r carries none the authenticator understands.
+	Authenticate(r *http.Request) (*Principal, error)
+	// Challenge is the WWW-Authenticate challenge for this method.
+	Challenge() string
+}
+
+// Chain tries each authenticator in turn and accepts the first principal
+// one of them returns.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	err := ErrNoCredentials
+	for _, a := range c {
+		principal, aerr := a.Authenticate(r)
+		if aerr == nil {
+			return principal, nil
+		}
+		// A credential one method rejects may still be valid for another,
+		// e.g. an API key sent as a bearer token.
+		if errors.Is(err, ErrNoCredentials) {
+			err = aerr
+		}
+	}
+	return nil, err
+}
+
+func (c Chain) Challenge() string {
+	challenges := make([]string, len(c))
+	for i, a := range c {
+		challenges[i] = a.Challenge()
+	}
+	return strings.Join(challenges, ", ")
+}
+
+// bearerToken returns the token of an Authorization: Bearer header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// hashSecret returns the hex SHA-256 of a random credential. Credentials
+// are stored and looked up by hash, so the stores hold nothing usable.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type principalKey struct{}
+
+// WithPrincipal records the authenticated principal of a request.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal of a request.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
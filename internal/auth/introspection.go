@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// introspectionCacheTTL bounds how long a token's introspection result
+	// is reused, and so how long a revoked token stays usable.
+	introspectionCacheTTL = time.Minute
+	// introspectionCacheSize is the number of results kept before expired
+	// ones are dropped.
+	introspectionCacheSize = 1024
+)
+
+// Introspection accepts bearer tokens issued by an external authorization
+// server, validating them at its introspection endpoint (RFC 7662).
+type Introspection struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	resource     *ProtectedResource
+	client       *http.Client
+
+	mu    sync.Mutex
+	cache map[string]introspected
+}
+
+// introspected is a cached introspection result; principal is nil for
+// inactive tokens.
+type introspected struct {
+	principal *Principal
+	until     time.Time
+}
+
+// NewIntrospection validates tokens for resource at endpoint, which the
+// server authenticates to with the given client credentials.
+func NewIntrospection(endpoint, clientID, clientSecret string, resource *ProtectedResource) *Introspection {
+	return &Introspection{
+		endpoint:     endpoint,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		resource:     resource,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		cache:        make(map[string]introspected),
+	}
+}
+
+func (i *Introspection) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	key := hashSecret(token)
+	now := time.Now()
+
+	i.mu.Lock()
+	cached, ok := i.cache[key]
+	i.mu.Unlock()
+
+	if !ok || now.After(cached.until) {
+		principal, err := i.introspect(r.Context(), token)
+		if err != nil {
+			return nil, err
+		}
+
+		cached = introspected{principal: principal, until: now.Add(introspectionCacheTTL)}
+		if principal != nil && !principal.ExpiresAt.IsZero() && principal.ExpiresAt.Before(cached.until) {
+			cached.until = principal.ExpiresAt
+		}
+		i.store(key, cached, now)
+	}
+
+	if cached.principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return cached.principal, nil
+}
+
+func (i *Introspection) Challenge() string {
+	return bearerChallenge(i.resource.MetadataURL())
+}
+
+// introspect asks the authorization server about token. It returns nil for
+// a token that is inactive or was issued for another resource.
+func (i *Introspection) introspect(ctx context.Context, token string) (*Principal, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("token introspection failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.clientSecret))
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token introspection failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token introspection failed: %s", resp.Status)
+	}
+
+	var result struct {
+		Active   bool            `json:"active"`
+		Subject  string          `json:"sub"`
+		Username string          `json:"username"`
+		Exp      int64           `json:"exp"`
+		Audience json.RawMessage `json:"aud"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("token introspection failed: %w", err)
+	}
+
+	if !result.Active || !i.forResource(result.Audience) {
+		return nil, nil
+	}
+
+	principal := &Principal{Subject: result.Subject, Method: "oauth"}
+	if principal.Subject == "" {
+		principal.Subject = result.Username
+	}
+	if principal.Subject == "" {
+		return nil, nil
+	}
+	if result.Exp != 0 {
+		principal.ExpiresAt = time.Unix(result.Exp, 0)
+	}
+	return principal, nil
+}
+
+// forResource reports whether a token's audience, a string or an array of
+// strings, includes this server. Tokens without an audience are accepted.
+func (i *Introspection) forResource(raw json.RawMessage) bool {
+	if len(raw) == 0 || string(raw) == "null" {
+		return true
+	}
+
+	var audience []string
+	if err := json.Unmarshal(raw, &audience); err != nil {
+		var single string
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return false
+		}
+		audience = []string{single}
+	}
+
+	for _, aud := range audience {
+		if i.resource.identifies(aud) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Introspection) store(key string, result introspected, now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.cache) >= introspectionCacheSize {
+		for k, cached := range i.cache {
+			if now.After(cached.until) {
+				delete(i.cache, k)
+			}
+		}
+	}
+	i.cache[key] = result
+}
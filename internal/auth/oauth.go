@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Well-known paths of the OAuth metadata documents MCP clients discover.
+const (
+	ProtectedResourcePath           = "/.well-known/oauth-protected-resource"
+	AuthorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+)
+
+// ProtectedResource is the protected resource metadata (RFC 9728) that
+// tells MCP clients which authorization server issues tokens for the
+// server.
+type ProtectedResource struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+// NewProtectedResource describes the server at publicURL as protected by
+// the authorization server issuer.
+func NewProtectedResource(publicURL, issuer string) *ProtectedResource {
+	return &ProtectedResource{
+		Resource:               publicURL,
+		AuthorizationServers:   []string{issuer},
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "GitHub MCP server",
+	}
+}
+
+// MetadataURL is where the metadata is served, as advertised in the
+// resource_metadata parameter of WWW-Authenticate challenges.
+func (p *ProtectedResource) MetadataURL() string {
+	return p.Resource + ProtectedResourcePath
+}
+
+// identifies reports whether a resource indicator (RFC 8707) or token
+// audience names this server or one of its endpoints, such as /mcp.
+func (p *ProtectedResource) identifies(uri string) bool {
+	return uri == p.Resource || strings.HasPrefix(uri, p.Resource+"/")
+}
+
+func (p *ProtectedResource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, p)
+}
+
+// ParsePublicURL validates the base URL clients reach the server at, which
+// identifies it as an OAuth resource and issuer. It must be https unless
+// the host is a loopback address.
+func ParsePublicURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("public url: %w", err)
+	}
+	if u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("public url %q: want an absolute URL without query or fragment", raw)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLoopback(u.Hostname())) {
+		return "", fmt.Errorf("public url %q: must use https", raw)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// bearerChallenge points clients at the protected resource metadata, as
+// the MCP authorization spec requires.
+func bearerChallenge(metadataURL string) string {
+	return fmt.Sprintf(`Bearer realm="github-mcp-http", resource_metadata=%q`, metadataURL)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeOAuthError writes an OAuth error response (RFC 6749, section 5.2).
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,732 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoints of the local authorization server.
+const (
+	AuthorizePath  = "/oauth/authorize"
+	TokenPath      = "/oauth/token"
+	RegisterPath   = "/oauth/register"
+	IntrospectPath = "/oauth/introspect"
+)
+
+const (
+	DefaultAccessTokenTTL  = time.Hour
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	authCodeTTL = time.Minute
+	// maxClients bounds dynamic registration, which is open to anyone.
+	maxClients = 1000
+	// unusedClientTTL is how long a registered client may go without
+	// completing an authorization before it is forgotten.
+	unusedClientTTL = time.Hour
+	maxFormSize     = 64 << 10
+)
+
+// Prefixes of the credentials the server issues, so they are recognizable
+// in logs and leaks.
+const (
+	clientIDPrefix     = "ghmcp_ci_"
+	clientSecretPrefix = "ghmcp_cs_"
+	authCodePrefix     = "ghmcp_ac_"
+	accessTokenPrefix  = "ghmcp_at_"
+	refreshTokenPrefix = "ghmcp_rt_"
+)
+
+// AuthorizationServer is a minimal in-process OAuth 2.1 authorization
+// server for self-hosted deployments. Clients register dynamically and
+// use the authorization code flow with PKCE; the user approves a client by
+// signing in with their API key, so API keys stay out of client configs.
+// State lives in memory, so a restart signs every client out.
+type AuthorizationServer struct {
+	resource   *ProtectedResource
+	login      *APIKeys
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*oauthClient
+	// codes, access and refresh are keyed by the hash of the credential.
+	codes   map[string]*authCode
+	access  map[string]*grant
+	refresh map[string]*grant
+}
+
+type oauthClient struct {
+	id           string
+	name         string
+	secretHash   string // empty for public clients
+	authMethod   string
+	redirectURIs []string
+	grantTypes   []string
+	registeredAt time.Time
+	// authorized is set once a user has approved the client and it has
+	// redeemed the code. Guarded by the server's mu.
+	authorized bool
+}
+
+type authCode struct {
+	clientID    string
+	redirectURI string
+	// redirectURIGiven is set when the authorization request named the
+	// redirect URI, which the token request must then repeat.
+	redirectURIGiven bool
+	challenge        string
+	principal        *Principal
+	expiresAt        time.Time
+}
+
+// grant is an issued access or refresh token.
+type grant struct {
+	clientID  string
+	principal *Principal
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// NewAuthorizationServer issues tokens for resource, whose base URL also
+// serves as the issuer. Users sign in with the API keys in login.
+func NewAuthorizationServer(resource *ProtectedResource, login *APIKeys) *AuthorizationServer {
+	return &AuthorizationServer{
+		resource:   resource,
+		login:      login,
+		accessTTL:  DefaultAccessTokenTTL,
+		refreshTTL: DefaultRefreshTokenTTL,
+		clients:    make(map[string]*oauthClient),
+		codes:      make(map[string]*authCode),
+		access:     make(map[string]*grant),
+		refresh:    make(map[string]*grant),
+	}
+}
+
+func (s *AuthorizationServer) issuer() string {
+	return s.resource.Resource
+}
+
+// ServeMetadata serves the authorization server metadata (RFC 8414).
+func (s *AuthorizationServer) ServeMetadata(w http.ResponseWriter, r *http.Request) {
+	issuer := s.issuer()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                         issuer,
+		"authorization_endpoint":                         issuer + AuthorizePath,
+		"token_endpoint":                                 issuer + TokenPath,
+		"registration_endpoint":                          issuer + RegisterPath,
+		"introspection_endpoint":                         issuer + IntrospectPath,
+		"response_types_supported":                       []string{"code"},
+		"grant_types_supported":                          []string{"authorization_code", "refresh_token"},
+		"code_challenge_methods_supported":               []string{"S256"},
+		"token_endpoint_auth_methods_supported":          []string{"none", "client_secret_basic", "client_secret_post"},
+		"introspection_endpoint_auth_methods_supported":  []string{"client_secret_basic", "client_secret_post"},
+		"authorization_response_iss_parameter_supported": true,
+	})
+}
+
+// HandleRegister implements dynamic client registration (RFC 7591).
+func (s *AuthorizationServer) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RedirectURIs            []string `json:"redirect_uris"`
+		ClientName              string   `json:"client_name"`
+		TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+		GrantTypes              []string `json:"grant_types"`
+		ResponseTypes           []string `json:"response_types"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFormSize)).Decode(&req); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Request body is not valid client metadata")
+		return
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", "At least one redirect URI is required")
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validRedirectURI(uri); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", err.Error())
+			return
+		}
+	}
+
+	client := &oauthClient{
+		name:         req.ClientName,
+		authMethod:   req.TokenEndpointAuthMethod,
+		redirectURIs: req.RedirectURIs,
+		grantTypes:   req.GrantTypes,
+		registeredAt: time.Now(),
+	}
+	switch client.authMethod {
+	case "":
+		client.authMethod = "client_secret_basic"
+	case "none", "client_secret_basic", "client_secret_post":
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported token_endpoint_auth_method "+client.authMethod)
+		return
+	}
+	if len(client.grantTypes) == 0 {
+		client.grantTypes = []string{"authorization_code"}
+	}
+	for _, grantType := range client.grantTypes {
+		if grantType != "authorization_code" && grantType != "refresh_token" {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported grant type "+grantType)
+			return
+		}
+	}
+	for _, responseType := range req.ResponseTypes {
+		if responseType != "code" {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported response type "+responseType)
+			return
+		}
+	}
+	if client.name == "" {
+		client.name = "An MCP client"
+	}
+
+	var err error
+	if client.id, err = randomCredential(clientIDPrefix); err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to generate client ID")
+		return
+	}
+	var secret string
+	if client.authMethod != "none" {
+		if secret, err = randomCredential(clientSecretPrefix); err != nil {
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to generate client secret")
+			return
+		}
+		client.secretHash = hashSecret(secret)
+	}
+
+	s.mu.Lock()
+	if !s.makeRoomForClient(client.registeredAt) {
+		s.mu.Unlock()
+		writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "Too many registered clients")
+		return
+	}
+	s.clients[client.id] = client
+	s.mu.Unlock()
+
+	resp := map[string]interface{}{
+		"client_id":                  client.id,
+		"client_id_issued_at":        time.Now().Unix(),
+		"client_name":                client.name,
+		"redirect_uris":              client.redirectURIs,
+		"token_endpoint_auth_method": client.authMethod,
+		"grant_types":                client.grantTypes,
+		"response_types":             []string{"code"},
+	}
+	if secret != "" {
+		resp["client_secret"] = secret
+		resp["client_secret_expires_at"] = 0
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// HandleAuthorize serves the authorization endpoint. A GET shows the sign
+// in page; the page POSTs back with the user's API key and decision.
+func (s *AuthorizationServer) HandleAuthorize(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		renderAuthorizeError(w, "The authorization request is malformed.")
+		return
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "state", "code_challenge", "code_challenge_method", "resource", "scope"} {
+		if value := r.Form.Get(name); value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	s.mu.Lock()
+	client, ok := s.clients[params.Get("client_id")]
+	s.mu.Unlock()
+	if !ok {
+		renderAuthorizeError(w, "The application is not registered with this server.")
+		return
+	}
+
+	redirectURI := params.Get("redirect_uri")
+	if redirectURI == "" && len(client.redirectURIs) == 1 {
+		redirectURI = client.redirectURIs[0]
+	}
+	if !redirectURIAllowed(client.redirectURIs, redirectURI) {
+		// Never redirect to an unregistered URI, not even with an error.
+		renderAuthorizeError(w, "The redirect URI is not registered for this application.")
+		return
+	}
+
+	reject := func(code, description string) {
+		s.redirect(w, r, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {params.Get("state")},
+		})
+	}
+
+	switch {
+	case params.Get("response_type") != "code":
+		reject("unsupported_response_type", "Only the code response type is supported")
+		return
+	case params.Get("code_challenge") == "":
+		reject("invalid_request", "PKCE is required: code_challenge is missing")
+		return
+	case params.Get("code_challenge_method") != "S256":
+		reject("invalid_request", "code_challenge_method must be S256")
+		return
+	case params.Has("resource") && !s.resource.identifies(params.Get("resource")):
+		reject("invalid_target", "Tokens can only be issued for "+s.resource.Resource)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		renderAuthorizePage(w, client.name, params, "")
+		return
+	}
+
+	if r.PostForm.Get("action") != "allow" {
+		reject("access_denied", "The user denied the request")
+		return
+	}
+
+	principal, ok := s.login.lookup(r.PostForm.Get("api_key"))
+	if !ok {
+		renderAuthorizePage(w, client.name, params, "That API key is not valid.")
+		return
+	}
+
+	code, err := randomCredential(authCodePrefix)
+	if err != nil {
+		reject("server_error", "Failed to issue an authorization code")
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[hashSecret(code)] = &authCode{
+		clientID:         client.id,
+		redirectURI:      redirectURI,
+		redirectURIGiven: params.Has("redirect_uri"),
+		challenge:        params.Get("code_challenge"),
+		principal:        principal,
+		expiresAt:        time.Now().Add(authCodeTTL),
+	}
+	s.mu.Unlock()
+
+	s.redirect(w, r, redirectURI, url.Values{"code": {code}, "state": {params.Get("state")}})
+}
+
+// redirect sends the user agent back to the client with params, plus the
+// issuer so the client can detect mix-up attacks (RFC 9207).
+func (s *AuthorizationServer) redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		renderAuthorizeError(w, "The redirect URI is malformed.")
+		return
+	}
+
+	query := u.Query()
+	for name, values := range params {
+		if values[0] != "" {
+			query[name] = values
+		}
+	}
+	query.Set("iss", s.issuer())
+	u.RawQuery = query.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// HandleToken serves the token endpoint for the authorization_code and
+// refresh_token grants. Refresh tokens are rotated on every use.
+func (s *AuthorizationServer) HandleToken(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Request body is malformed")
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="github-mcp-http"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	if grantType != "authorization_code" && grantType != "refresh_token" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code and refresh_token grants are supported")
+		return
+	}
+	if !client.allowsGrant(grantType) {
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "The client is not registered for the "+grantType+" grant")
+		return
+	}
+
+	var principal *Principal
+	if grantType == "authorization_code" {
+		principal, ok = s.redeemCode(client, r.PostForm)
+	} else {
+		principal, ok = s.redeemRefreshToken(client, r.PostForm.Get("refresh_token"))
+	}
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "The grant is invalid, expired or was issued to another client")
+		return
+	}
+
+	accessToken, err := randomCredential(accessTokenPrefix)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue a token")
+		return
+	}
+	// Only clients registered for the refresh_token grant get a refresh
+	// token.
+	var refreshToken string
+	if client.allowsGrant("refresh_token") {
+		if refreshToken, err = randomCredential(refreshTokenPrefix); err != nil {
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue a token")
+			return
+		}
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.sweep(now)
+	s.access[hashSecret(accessToken)] = &grant{clientID: client.id, principal: principal, issuedAt: now, expiresAt: now.Add(s.accessTTL)}
+	if refreshToken != "" {
+		s.refresh[hashSecret(refreshToken)] = &grant{clientID: client.id, principal: principal, issuedAt: now, expiresAt: now.Add(s.refreshTTL)}
+	}
+	s.mu.Unlock()
+
+	resp := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.accessTTL.Seconds()),
+	}
+	if refreshToken != "" {
+		resp["refresh_token"] = refreshToken
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// allowsGrant reports whether the client registered for grantType.
+func (c *oauthClient) allowsGrant(grantType string) bool {
+	for _, registered := range c.grantTypes {
+		if registered == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// redeemCode consumes an authorization code, checking that it was issued
+// to client for the same redirect URI and that the PKCE verifier matches.
+// The redirect URI must be repeated if the authorization request gave one.
+func (s *AuthorizationServer) redeemCode(client *oauthClient, form url.Values) (*Principal, bool) {
+	s.mu.Lock()
+	key := hashSecret(form.Get("code"))
+	code, ok := s.codes[key]
+	delete(s.codes, key)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) || code.clientID != client.id {
+		return nil, false
+	}
+	if (code.redirectURIGiven || form.Has("redirect_uri")) && form.Get("redirect_uri") != code.redirectURI {
+		return nil, false
+	}
+
+	verifier := form.Get("code_verifier")
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return nil, false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.challenge)) != 1 {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	client.authorized = true
+	s.mu.Unlock()
+	return code.principal, true
+}
+
+func (s *AuthorizationServer) redeemRefreshToken(client *oauthClient, token string) (*Principal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashSecret(token)
+	g, ok := s.refresh[key]
+	if !ok || g.clientID != client.id {
+		return nil, false
+	}
+	delete(s.refresh, key)
+	if time.Now().After(g.expiresAt) {
+		return nil, false
+	}
+	return g.principal, true
+}
+
+// HandleIntrospect serves token introspection (RFC 7662) to confidential
+// clients. Registration is open to anyone, so a client may only introspect
+// the tokens issued to it; every other token is reported inactive.
+func (s *AuthorizationServer) HandleIntrospect(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Request body is malformed")
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok || client.secretHash == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="github-mcp-http"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Introspection requires a confidential client")
+		return
+	}
+
+	key := hashSecret(r.PostForm.Get("token"))
+	s.mu.Lock()
+	g, ok := s.access[key]
+	tokenType := "Bearer"
+	if !ok {
+		g, ok = s.refresh[key]
+		tokenType = "refresh_token"
+	}
+	s.mu.Unlock()
+
+	if !ok || g.clientID != client.id || time.Now().After(g.expiresAt) {
+		writeJSON(w, http.StatusOK, map[string]bool{"active": false})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active":     true,
+		"client_id":  g.clientID,
+		"sub":        g.principal.Subject,
+		"token_type": tokenType,
+		"iat":        g.issuedAt.Unix(),
+		"exp":        g.expiresAt.Unix(),
+		"iss":        s.issuer(),
+		"aud":        s.resource.Resource,
+	})
+}
+
+// authenticateClient identifies the client of a token or introspection
+// request by client_secret_basic, client_secret_post or, for public
+// clients, client_id alone.
+func (s *AuthorizationServer) authenticateClient(r *http.Request) (*oauthClient, bool) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// Basic credentials are form-encoded (RFC 6749, section 2.3.1).
+		var err error
+		if id, err = url.QueryUnescape(id); err != nil {
+			return nil, false
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return nil, false
+		}
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	client, ok := s.clients[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	if client.secretHash == "" {
+		return client, secret == ""
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.secretHash)) != 1 {
+		return nil, false
+	}
+	return client, true
+}
+
+// Authenticate accepts access tokens issued by this server.
+func (s *AuthorizationServer) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok || !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	s.mu.Lock()
+	g, ok := s.access[hashSecret(token)]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if time.Now().After(g.expiresAt) {
+		return nil, ErrExpired
+	}
+	return &Principal{Subject: g.principal.Subject, Method: "oauth", ExpiresAt: g.expiresAt}, nil
+}
+
+func (s *AuthorizationServer) Challenge() string {
+	return bearerChallenge(s.resource.MetadataURL())
+}
+
+// makeRoomForClient reports whether another client can be registered.
+// Anyone can register, so clients that never complete an authorization
+// expire, and when the server is full the oldest of them makes way; only
+// clients a user has approved are never evicted. The caller must hold
+// s.mu.
+func (s *AuthorizationServer) makeRoomForClient(now time.Time) bool {
+	if len(s.clients) < maxClients {
+		return true
+	}
+
+	var oldest *oauthClient
+	for id, client := range s.clients {
+		if client.authorized {
+			continue
+		}
+		if now.Sub(client.registeredAt) > unusedClientTTL {
+			delete(s.clients, id)
+			continue
+		}
+		if oldest == nil || client.registeredAt.Before(oldest.registeredAt) {
+			oldest = client
+		}
+	}
+
+	if len(s.clients) < maxClients {
+		return true
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(s.clients, oldest.id)
+	return true
+}
+
+// sweep drops expired codes and tokens. The caller must hold s.mu.
+func (s *AuthorizationServer) sweep(now time.Time) {
+	for key, code := range s.codes {
+		if now.After(code.expiresAt) {
+			delete(s.codes, key)
+		}
+	}
+	for _, grants := range []map[string]*grant{s.access, s.refresh} {
+		for key, g := range grants {
+			if now.After(g.expiresAt) {
+				delete(grants, key)
+			}
+		}
+	}
+}
+
+// validRedirectURI accepts https URIs, http URIs on loopback addresses and
+// the private-use schemes of native apps (OAuth 2.1, section 8.4).
+func validRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URI without a fragment", raw)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return fmt.Errorf("redirect URI %q must use https unless it is a loopback address", raw)
+		}
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("redirect URI %q uses a forbidden scheme", raw)
+	}
+	return nil
+}
+
+// redirectURIAllowed reports whether uri is one of the registered URIs.
+// Loopback URIs match on any port, since native apps bind an ephemeral
+// port for each authorization.
+func redirectURIAllowed(registered []string, uri string) bool {
+	for _, candidate := range registered {
+		if candidate == uri || sameLoopbackURI(candidate, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameLoopbackURI(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil || ua.Scheme != "http" || !isLoopback(ua.Hostname()) {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ub.Scheme == "http" && ua.Hostname() == ub.Hostname() && ua.Path == ub.Path && ua.RawQuery == ub.RawQuery
+}
+
+func randomCredential(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorize {{.Client}}</title></head>
+<body>
+<h1>Authorize {{.Client}}</h1>
+<p>{{.Client}} wants to use this GitHub MCP server on your behalf. Sign in with your API key to allow it.</p>
+{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+<form method="post">
+{{range $name, $values := .Params}}<input type="hidden" name="{{$name}}" value="{{index $values 0}}">
+{{end}}<label>API key <input type="password" name="api_key" autocomplete="off" autofocus></label>
+<button type="submit" name="action" value="allow">Allow</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+var authorizeErrorPage = template.Must(template.New("authorize-error").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorization failed</title></head>
+<body>
+<h1>Authorization failed</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+func renderAuthorizePage(w http.ResponseWriter, client string, params url.Values, message string) {
+	setPageHeaders(w)
+	authorizePage.Execute(w, map[string]interface{}{
+		"Client": client,
+		"Params": params,
+		"Error":  message,
+	})
+}
+
+func renderAuthorizeError(w http.ResponseWriter, message string) {
+	setPageHeaders(w)
+	w.WriteHeader(http.StatusBadRequest)
+	authorizeErrorPage.Execute(w, message)
+}
+
+// setPageHeaders keeps the sign in page from being framed or cached.
+func setPageHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+}
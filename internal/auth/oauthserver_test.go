@@ -0,0 +1,342 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthorizationServer(t *testing.T) *AuthorizationServer {
+	t.Helper()
+	keys, err := ParseAPIKeys([]string{"alice:" + HashAPIKey("ghmcp_alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthorizationServer(NewProtectedResource("https://mcp.example.com", "https://mcp.example.com"), keys)
+}
+
+func register(t *testing.T, s *AuthorizationServer, body string) (int, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.HandleRegister(rec, httptest.NewRequest(http.MethodPost, RegisterPath, strings.NewReader(body)))
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp
+}
+
+func TestRegistrationFloodEvictsUnusedClients(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	const body = `{"redirect_uris":["http://127.0.0.1/callback"],"token_endpoint_auth_method":"none"}`
+
+	_, approved := register(t, s, body)
+	approvedID := approved["client_id"].(string)
+	s.clients[approvedID].authorized = true
+	s.clients[approvedID].registeredAt = time.Now().Add(-2 * unusedClientTTL)
+
+	_, first := register(t, s, body)
+	firstID := first["client_id"].(string)
+	for len(s.clients) < maxClients {
+		register(t, s, body)
+	}
+
+	code, _ := register(t, s, body)
+	if code != http.StatusCreated {
+		t.Fatalf("registration on a full server = %d, want %d", code, http.StatusCreated)
+	}
+	if len(s.clients) != maxClients {
+		t.Errorf("%d clients registered, want %d", len(s.clients), maxClients)
+	}
+	if _, ok := s.clients[approvedID]; !ok {
+		t.Error("an approved client was evicted")
+	}
+	if _, ok := s.clients[firstID]; ok {
+		t.Error("the oldest unused client was not evicted")
+	}
+}
+
+func TestRegistrationExpiresUnusedClients(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	const body = `{"redirect_uris":["http://127.0.0.1/callback"],"token_endpoint_auth_method":"none"}`
+
+	for len(s.clients) < maxClients {
+		register(t, s, body)
+	}
+	for _, client := range s.clients {
+		client.registeredAt = time.Now().Add(-2 * unusedClientTTL)
+	}
+
+	if code, _ := register(t, s, body); code != http.StatusCreated {
+		t.Fatalf("registration = %d, want %d", code, http.StatusCreated)
+	}
+	if len(s.clients) != 1 {
+		t.Errorf("%d clients registered, want only the new one", len(s.clients))
+	}
+}
+
+// authorize approves client as alice and returns the authorization code.
+func authorize(t *testing.T, s *AuthorizationServer, clientID, redirectURI, challenge string) string {
+	t.Helper()
+	form := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+		"action":                {"allow"},
+		"api_key":               {"ghmcp_alice"},
+	}
+	r := httptest.NewRequest(http.MethodPost, AuthorizePath, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.HandleAuthorize(rec, r)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || err != nil {
+		t.Fatalf("authorize = %d, %s", rec.Code, rec.Body)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in redirect to %s", location)
+	}
+	return code
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestAuthorizationCodeRequiresPKCE(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	const redirectURI = "http://127.0.0.1:8123/callback"
+	_, client := register(t, s, `{"redirect_uris":["http://127.0.0.1/callback"],"token_endpoint_auth_method":"none"}`)
+	clientID := client["client_id"].(string)
+	verifier := strings.Repeat("v", 43)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		requested string
+		redirect  string
+		want      int
+	}{
+		{"matching verifier", verifier, redirectURI, redirectURI, http.StatusOK},
+		{"redirect_uri in neither request", verifier, "", "", http.StatusOK},
+		{"redirect_uri only at the token endpoint", verifier, "", "http://127.0.0.1/callback", http.StatusOK},
+		{"without redirect_uri", verifier, redirectURI, "", http.StatusBadRequest},
+		{"wrong verifier", strings.Repeat("w", 43), redirectURI, redirectURI, http.StatusBadRequest},
+		{"short verifier", verifier[:42], redirectURI, redirectURI, http.StatusBadRequest},
+		{"no verifier", "", redirectURI, redirectURI, http.StatusBadRequest},
+		{"other redirect_uri", verifier, redirectURI, "http://127.0.0.1:9999/callback", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		// Loopback redirect URIs match the registered one on any port.
+		code := authorize(t, s, clientID, tt.requested, pkceChallenge(verifier))
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {clientID},
+			"code":          {code},
+			"code_verifier": {tt.verifier},
+		}
+		if tt.redirect != "" {
+			form.Set("redirect_uri", tt.redirect)
+		}
+		r := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.HandleToken(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("%s: token = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+			continue
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &token)
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		if principal, err := s.Authenticate(req); err != nil || principal.String() != "oauth:alice" {
+			t.Errorf("%s: access token authenticates as %v, %v", tt.name, principal, err)
+		}
+
+		// Codes are single use.
+		rec = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		s.HandleToken(rec, r)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: reused code = %d, want %d", tt.name, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAuthorizeRejectsPlainPKCE(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	_, client := register(t, s, `{"redirect_uris":["https://app.example.com/callback"],"token_endpoint_auth_method":"none"}`)
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client["client_id"].(string)},
+		"code_challenge":        {"challenge"},
+		"code_challenge_method": {"plain"},
+	}
+	rec := httptest.NewRecorder()
+	s.HandleAuthorize(rec, httptest.NewRequest(http.MethodGet, AuthorizePath+"?"+query.Encode(), nil))
+
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || location.Query().Get("error") != "invalid_request" {
+		t.Errorf("plain PKCE: %d to %s, want an invalid_request redirect", rec.Code, location)
+	}
+}
+
+func TestRedirectURIs(t *testing.T) {
+	registered := []string{"https://app.example.com/callback", "http://127.0.0.1/callback", "http://localhost:3000/cb?x=1"}
+
+	tests := []struct {
+		uri     string
+		valid   bool
+		allowed bool
+	}{
+		{"https://app.example.com/callback", true, true},
+		{"https://app.example.com/callback/", true, false},
+		{"https://app.example.com:8443/callback", true, false},
+		{"https://evil.example.com/callback", true, false},
+		{"http://127.0.0.1/callback", true, true},
+		{"http://127.0.0.1:49152/callback", true, true},
+		{"http://127.0.0.1:49152/other", true, false},
+		{"https://127.0.0.1:49152/callback", true, false},
+		{"http://localhost:4000/cb?x=1", true, true},
+		{"http://localhost:4000/cb?x=2", true, false},
+		{"http://[::1]:4000/callback", true, false},
+		{"http://app.example.com/callback", false, false},
+		{"com.example.app:/callback", true, false},
+		{"https://app.example.com/callback#fragment", false, false},
+		{"/callback", false, false},
+		{"javascript:alert(1)", false, false},
+	}
+	for _, tt := range tests {
+		if err := validRedirectURI(tt.uri); (err == nil) != tt.valid {
+			t.Errorf("validRedirectURI(%q) = %v, want valid %v", tt.uri, err, tt.valid)
+		}
+		if got := redirectURIAllowed(registered, tt.uri); got != tt.allowed {
+			t.Errorf("redirectURIAllowed(%q) = %v, want %v", tt.uri, got, tt.allowed)
+		}
+	}
+}
+
+func requestToken(s *AuthorizationServer, form url.Values) (int, map[string]interface{}) {
+	r := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.HandleToken(rec, r)
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp
+}
+
+func TestTokenFollowsRegisteredGrantTypes(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	const redirectURI = "https://app.example.com/callback"
+	verifier := strings.Repeat("v", 43)
+
+	tests := []struct {
+		name       string
+		grantTypes string
+		refresh    bool
+	}{
+		{"default grant types", ``, false},
+		{"authorization_code only", `,"grant_types":["authorization_code"]`, false},
+		{"with refresh_token", `,"grant_types":["authorization_code","refresh_token"]`, true},
+	}
+	for _, tt := range tests {
+		_, client := register(t, s, `{"redirect_uris":["`+redirectURI+`"],"token_endpoint_auth_method":"none"`+tt.grantTypes+`}`)
+		clientID := client["client_id"].(string)
+
+		status, token := requestToken(s, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {clientID},
+			"code":          {authorize(t, s, clientID, redirectURI, pkceChallenge(verifier))},
+			"redirect_uri":  {redirectURI},
+			"code_verifier": {verifier},
+		})
+		if status != http.StatusOK {
+			t.Errorf("%s: token = %d, %v", tt.name, status, token)
+			continue
+		}
+		refreshToken, issued := token["refresh_token"].(string)
+		if issued != tt.refresh {
+			t.Errorf("%s: refresh token issued = %v, want %v", tt.name, issued, tt.refresh)
+		}
+
+		status, resp := requestToken(s, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"refresh_token": {refreshToken},
+		})
+		switch {
+		case tt.refresh && status != http.StatusOK:
+			t.Errorf("%s: refresh = %d, %v", tt.name, status, resp)
+		case !tt.refresh && resp["error"] != "unauthorized_client":
+			t.Errorf("%s: refresh = %d, %v, want unauthorized_client", tt.name, status, resp)
+		}
+	}
+}
+
+func TestIntrospectionIsLimitedToOwnTokens(t *testing.T) {
+	s := newTestAuthorizationServer(t)
+	const redirectURI = "https://app.example.com/callback"
+	verifier := strings.Repeat("v", 43)
+
+	newClient := func() (string, string) {
+		_, client := register(t, s, `{"redirect_uris":["`+redirectURI+`"],"token_endpoint_auth_method":"client_secret_post"}`)
+		return client["client_id"].(string), client["client_secret"].(string)
+	}
+	ownerID, ownerSecret := newClient()
+	otherID, otherSecret := newClient()
+
+	_, token := requestToken(s, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {ownerID},
+		"client_secret": {ownerSecret},
+		"code":          {authorize(t, s, ownerID, redirectURI, pkceChallenge(verifier))},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	accessToken, _ := token["access_token"].(string)
+
+	tests := []struct {
+		name   string
+		id     string
+		secret string
+		active bool
+	}{
+		{"client the token was issued to", ownerID, ownerSecret, true},
+		{"another client", otherID, otherSecret, false},
+	}
+	for _, tt := range tests {
+		form := url.Values{"token": {accessToken}, "client_id": {tt.id}, "client_secret": {tt.secret}}
+		r := httptest.NewRequest(http.MethodPost, IntrospectPath, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.HandleIntrospect(rec, r)
+
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp["active"] != tt.active {
+			t.Errorf("%s: introspection = %s, want active %v", tt.name, rec.Body, tt.active)
+		}
+		if _, ok := resp["sub"]; ok && !tt.active {
+			t.Errorf("%s: introspection revealed the subject", tt.name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// minTokenSecretSize is the shortest secret tokens may be signed with.
+const minTokenSecretSize = 32
+
+// Tokens issues and verifies self-contained bearer tokens: a JSON payload
+// naming the subject and expiry, signed with HMAC-SHA256. Anyone holding
+// the secret can issue tokens, so it must be kept like a password.
+type Tokens struct {
+	secret []byte
+}
+
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func NewTokens(secret string) (*Tokens, error) {
+	if len(secret) < minTokenSecretSize {
+		return nil, fmt.Errorf("token secret must be at least %d bytes", minTokenSecretSize)
+	}
+	return &Tokens{secret: []byte(secret)}, nil
+}
+
+// Issue returns a token for subject that is valid for ttl.
+func (t *Tokens) Issue(subject string, ttl time.Duration) (string, error) {
+	if subject == "" {
+		return "", errors.New("token subject is required")
+	}
+	if ttl <= 0 {
+		return "", errors.New("token lifetime must be positive")
+	}
+
+	payload, err := json.Marshal(tokenClaims{Subject: subject, ExpiresAt: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(encoded)), nil
+}
+
+func (t *Tokens) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		// Not a signed token; it may be an API key or OAuth token.
+		return nil, ErrNoCredentials
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, t.sign(encoded)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !time.Now().Before(expiresAt) {
+		return nil, ErrExpired
+	}
+	return &Principal{Subject: claims.Subject, Method: "token", ExpiresAt: expiresAt}, nil
+}
+
+func (t *Tokens) Challenge() string {
+	return `Bearer realm="github-mcp-http"`
+}
+
+func (t *Tokens) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTokenSecret = "0123456789abcdef0123456789abcdef"
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestTokens(t *testing.T) {
+	tokens, err := NewTokens(testTokenSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := tokens.Issue("alice", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewTokens(strings.Repeat("x", minTokenSecretSize))
+	forged, _ := other.Issue("alice", time.Hour)
+	payload, signature, _ := strings.Cut(valid, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","exp":9999999999}`)) + "." + signature
+
+	// Issue can only make tokens that are still valid, so sign an expired
+	// one directly.
+	claims, _ := json.Marshal(tokenClaims{Subject: "alice", ExpiresAt: time.Now().Add(-time.Second).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(claims)
+	expired := encoded + "." + base64.RawURLEncoding.EncodeToString(tokens.sign(encoded))
+
+	tests := []struct {
+		name    string
+		token   string
+		subject string
+		err     error
+	}{
+		{"valid", valid, "alice", nil},
+		{"no header", "", "", ErrNoCredentials},
+		{"not a signed token", "ghmcp_abc", "", ErrNoCredentials},
+		{"signed with another secret", forged, "", ErrInvalidCredentials},
+		{"tampered payload", tampered, "", ErrInvalidCredentials},
+		{"bad signature encoding", payload + ".!!!", "", ErrInvalidCredentials},
+		{"expired", expired, "", ErrExpired},
+	}
+	for _, tt := range tests {
+		principal, err := tokens.Authenticate(bearerRequest(tt.token))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && (principal.Subject != tt.subject || principal.Method != "token") {
+			t.Errorf("%s: principal = %+v", tt.name, principal)
+		}
+	}
+}
+
+func TestNewTokensRejectsShortSecrets(t *testing.T) {
+	if _, err := NewTokens(testTokenSecret[:minTokenSecretSize-1]); err == nil {
+		t.Error("a short secret was accepted")
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	key, entry, err := GenerateAPIKey("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		t.Errorf("key %q lacks the %s prefix", key, apiKeyPrefix)
+	}
+	// Hashes are matched regardless of case.
+	name, hash, _ := strings.Cut(entry, ":")
+	keys, err := ParseAPIKeys([]string{name + ":" + strings.ToUpper(hash)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		bearer string
+		err    error
+	}{
+		{"header", key, "", nil},
+		{"bearer", "", key, nil},
+		{"unknown key", "ghmcp_unknown", "", ErrInvalidCredentials},
+		{"no credentials", "", "", ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		r := bearerRequest(tt.bearer)
+		if tt.header != "" {
+			r.Header.Set(APIKeyHeader, tt.header)
+		}
+		principal, err := keys.Authenticate(r)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && principal.String() != "api_key:alice" {
+			t.Errorf("%s: principal = %s", tt.name, principal)
+		}
+	}
+}
+
+func TestParseAPIKeysRejectsMalformedEntries(t *testing.T) {
+	for _, entry := range []string{
+		"alice",
+		":" + HashAPIKey("k"),
+		"alice:not-hex",
+		"alice:" + HashAPIKey("k")[:32],
+	} {
+		if _, err := ParseAPIKeys([]string{entry}); err == nil {
+			t.Errorf("ParseAPIKeys(%q) succeeded", entry)
+		}
+	}
+}
+
+func TestChainPrefersSpecificErrors(t *testing.T) {
+	tokens, _ := NewTokens(testTokenSecret)
+	keys, _ := ParseAPIKeys([]string{"alice:" + HashAPIKey("ghmcp_alice")})
+	chain := Chain{tokens, keys}
+
+	if principal, err := chain.Authenticate(bearerRequest("ghmcp_alice")); err != nil || principal.Subject != "alice" {
+		t.Errorf("API key as bearer token: %v, %v", principal, err)
+	}
+	if _, err := chain.Authenticate(bearerRequest("ghmcp_mallory")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown key: err = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := chain.Authenticate(bearerRequest("")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no credentials: err = %v, want %v", err, ErrNoCredentials)
+	}
+}
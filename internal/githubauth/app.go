@@ -0,0 +1,349 @@
+package githubauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// jwtLifetime is how long an app JWT is valid. GitHub accepts at most
+	// ten minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew backdates JWTs in case GitHub's clock is behind ours.
+	jwtClockSkew = time.Minute
+	// tokenRefreshMargin is how long before it expires an installation
+	// token is replaced, so requests in flight never carry a stale one.
+	tokenRefreshMargin = 5 * time.Minute
+	// discoveryInterval limits how often installations are listed again to
+	// look for an owner the app was not installed on before.
+	discoveryInterval = time.Minute
+	// requestTimeout bounds each call the app makes to GitHub on its own
+	// behalf, which no caller's context covers.
+	requestTimeout = 30 * time.Second
+)
+
+// AppConfig describes a GitHub App the server authenticates as.
+type AppConfig struct {
+	ID int64
+	// PrivateKey is the app's PEM encoded RSA private key.
+	PrivateKey []byte
+	// DefaultOwner is the account whose installation serves requests that
+	// concern no installed owner, such as GraphQL queries and searches. It
+	// may be empty when the app has a single installation.
+	DefaultOwner string
+	// Transport carries the app's requests to GitHub. Nil means
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// App authenticates as a GitHub App. It discovers the app's installations
+// and mints an installation token for each owner on demand, replacing
+// tokens before they expire.
+type App struct {
+	id           int64
+	key          *rsa.PrivateKey
+	defaultOwner string
+	// client authenticates as the app itself, with JWTs.
+	client *github.Client
+
+	mu sync.Mutex
+	// installations maps lower-cased account logins to installation IDs.
+	installations map[string]int64
+	discoveredAt  time.Time
+	// discovery is the listing of installations in flight, if any.
+	discovery *discovery
+	sources   map[int64]oauth2.TokenSource
+}
+
+// discovery is one listing of installations, shared by everyone who needs
+// it while it runs. err is set before done is closed.
+type discovery struct {
+	done chan struct{}
+	err  error
+}
+
+func NewApp(config AppConfig) (*App, error) {
+	if config.ID <= 0 {
+		return nil, errors.New("GitHub App ID is required")
+	}
+	key, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	a := &App{
+		id:            config.ID,
+		key:           key,
+		defaultOwner:  config.DefaultOwner,
+		installations: make(map[string]int64),
+		sources:       make(map[int64]oauth2.TokenSource),
+	}
+	a.client = github.NewClient(&http.Client{Transport: &jwtTransport{app: a, base: transport}})
+	return a, nil
+}
+
+// parsePrivateKey accepts the PKCS#1 keys GitHub generates as well as
+// PKCS#8.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("GitHub App private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// jwt returns a JWT that authenticates as the app, signed with RS256.
+func (a *App) jwt() (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": strconv.FormatInt(a.id, 10),
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// TokenSource returns installation tokens for owner. Owners the app is not
+// installed on, and the empty owner, get the default installation.
+func (a *App) TokenSource(owner string) oauth2.TokenSource {
+	return ownerTokenSource{app: a, owner: owner}
+}
+
+type ownerTokenSource struct {
+	app   *App
+	owner string
+}
+
+func (s ownerTokenSource) Token() (*oauth2.Token, error) {
+	source, err := s.app.installationSource(context.Background(), s.owner)
+	if err != nil {
+		return nil, err
+	}
+	return source.Token()
+}
+
+// installationSource returns the token source of the installation that
+// serves owner. Owners sharing an installation share its tokens.
+func (a *App) installationSource(ctx context.Context, owner string) (oauth2.TokenSource, error) {
+	id, err := a.installationID(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	source, ok := a.sources[id]
+	if !ok {
+		source = oauth2.ReuseTokenSourceWithExpiry(nil, &installationTokenSource{app: a, id: id}, tokenRefreshMargin)
+		a.sources[id] = source
+	}
+	return source, nil
+}
+
+// installationID finds the installation that serves owner, listing
+// installations again first if that might change the answer.
+func (a *App) installationID(ctx context.Context, owner string) (int64, error) {
+	a.mu.Lock()
+	id, stale, err := a.resolve(owner)
+	a.mu.Unlock()
+	if !stale {
+		return id, err
+	}
+
+	if err := a.discover(ctx); err != nil {
+		return 0, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id, _, err = a.resolve(owner)
+	return id, err
+}
+
+// resolve finds the installation that serves owner among those already
+// listed. It reports stale when the answer may change once installations
+// are listed again. The caller must hold a.mu.
+func (a *App) resolve(owner string) (int64, bool, error) {
+	canRefresh := time.Since(a.discoveredAt) >= discoveryInterval
+
+	if owner != "" {
+		if id, ok := a.installations[strings.ToLower(owner)]; ok {
+			return id, false, nil
+		}
+		if canRefresh {
+			return 0, true, nil
+		}
+	}
+
+	if a.defaultOwner != "" {
+		if id, ok := a.installations[strings.ToLower(a.defaultOwner)]; ok {
+			return id, false, nil
+		}
+		if canRefresh {
+			return 0, true, nil
+		}
+		return 0, false, fmt.Errorf("the GitHub App is not installed on %s", a.defaultOwner)
+	}
+
+	// Without a default owner, an app installed once has an obvious
+	// default.
+	if a.discoveredAt.IsZero() {
+		return 0, true, nil
+	}
+	ids := make(map[int64]bool)
+	for _, id := range a.installations {
+		ids[id] = true
+	}
+	if len(ids) == 1 {
+		for id := range ids {
+			return id, false, nil
+		}
+	}
+
+	if owner != "" {
+		return 0, false, fmt.Errorf("the GitHub App is not installed on %s", owner)
+	}
+	return 0, false, fmt.Errorf("the GitHub App has %d installations; configure the owner whose installation serves requests that concern none", len(ids))
+}
+
+// discover lists the app's installations. The listing runs without a.mu
+// held, so requests whose installation is known carry on meanwhile, and
+// callers that need it while it runs wait for the same listing.
+func (a *App) discover(ctx context.Context) error {
+	a.mu.Lock()
+	if d := a.discovery; d != nil {
+		a.mu.Unlock()
+		select {
+		case <-d.done:
+			return d.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	d := &discovery{done: make(chan struct{})}
+	a.discovery = d
+	a.mu.Unlock()
+
+	installations, err := a.listInstallations(ctx)
+
+	a.mu.Lock()
+	if err == nil {
+		a.installations = installations
+		a.discoveredAt = time.Now()
+	}
+	a.discovery = nil
+	d.err = err
+	a.mu.Unlock()
+	close(d.done)
+	return err
+}
+
+// listInstallations maps the accounts the app is installed on to their
+// installations.
+func (a *App) listInstallations(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	installations := make(map[string]int64)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := a.client.Apps.ListInstallations(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub App installations: %w", err)
+		}
+		for _, installation := range page {
+			installations[strings.ToLower(installation.GetAccount().GetLogin())] = installation.GetID()
+		}
+		if resp.NextPage == 0 {
+			return installations, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// installationTokenSource mints a new token for an installation on every
+// call; the app wraps it to reuse each token until shortly before it
+// expires.
+type installationTokenSource struct {
+	app *App
+	id  int64
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	token, _, err := s.app.client.Apps.CreateInstallationToken(ctx, s.id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+	return &oauth2.Token{AccessToken: token.GetToken(), Expiry: token.GetExpiresAt().Time}, nil
+}
+
+// jwtTransport authenticates requests as the app itself.
+type jwtTransport struct {
+	app  *App
+	base http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.app.jwt()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
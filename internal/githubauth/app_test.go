@@ -0,0 +1,208 @@
+package githubauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGitHub serves the app endpoints an App uses, checking every JWT.
+type fakeGitHub struct {
+	t      *testing.T
+	key    *rsa.PublicKey
+	minted atomic.Int32
+	// hang, when set, holds installation listings until it is closed.
+	hang chan struct{}
+	// expiresIn is the lifetime of minted tokens.
+	expiresIn time.Duration
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+	if len(parts) != 3 {
+		f.t.Errorf("%s: no JWT", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		f.t.Errorf("%s: JWT signature: %v", r.URL.Path, err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+		Issued int64  `json:"iat"`
+		Exp    int64  `json:"exp"`
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(payload, &claims)
+	if claims.Issuer != "42" || claims.Exp-claims.Issued > int64((10*time.Minute).Seconds()) {
+		f.t.Errorf("%s: JWT claims %+v", r.URL.Path, claims)
+	}
+
+	switch {
+	case r.URL.Path == "/app/installations":
+		if f.hang != nil {
+			select {
+			case <-f.hang:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s/app/installations?page=2>; rel="next"`, r.Host))
+			fmt.Fprint(w, `[{"id":1,"account":{"login":"Acme"}}]`)
+		} else {
+			fmt.Fprint(w, `[{"id":2,"account":{"login":"octocat"}}]`)
+		}
+	case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+		n := f.minted.Add(1)
+		installation := strings.Split(r.URL.Path, "/")[3]
+		fmt.Fprintf(w, `{"token":"token-%s-%d","expires_at":%q}`, installation, n, time.Now().Add(f.expiresIn).Format(time.RFC3339))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestApp(t *testing.T, defaultOwner string) (*App, *fakeGitHub) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeGitHub{t: t, key: &key.PublicKey, expiresIn: time.Hour}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	app, err := NewApp(AppConfig{
+		ID:           42,
+		PrivateKey:   pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		DefaultOwner: defaultOwner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.client.BaseURL, _ = url.Parse(server.URL + "/")
+	return app, fake
+}
+
+func TestAppInstallationTokens(t *testing.T) {
+	app, _ := newTestApp(t, "acme")
+
+	tests := []struct {
+		owner string
+		want  string
+	}{
+		{"ACME", "token-1-1"},
+		{"acme", "token-1-1"},
+		{"", "token-1-1"},
+		{"stranger", "token-1-1"},
+		{"octocat", "token-2-2"},
+		{"OctoCat", "token-2-2"},
+	}
+	for _, tt := range tests {
+		token, err := app.TokenSource(tt.owner).Token()
+		if err != nil {
+			t.Errorf("Token(%q): %v", tt.owner, err)
+			continue
+		}
+		if token.AccessToken != tt.want {
+			t.Errorf("Token(%q) = %s, want %s", tt.owner, token.AccessToken, tt.want)
+		}
+	}
+}
+
+func TestAppRefreshesTokensBeforeExpiry(t *testing.T) {
+	app, fake := newTestApp(t, "acme")
+	fake.expiresIn = tokenRefreshMargin / 2
+
+	first, err := app.TokenSource("acme").Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := app.TokenSource("acme").Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.AccessToken == second.AccessToken {
+		t.Error("a token within the refresh margin was reused")
+	}
+}
+
+func TestAppWithoutDefaultOwner(t *testing.T) {
+	app, _ := newTestApp(t, "")
+
+	if _, err := app.TokenSource("").Token(); err == nil || !strings.Contains(err.Error(), "2 installations") {
+		t.Errorf("Token with several installations and no default: %v", err)
+	}
+	if _, err := app.TokenSource("stranger").Token(); err == nil || !strings.Contains(err.Error(), "not installed on stranger") {
+		t.Errorf("Token for an owner without an installation: %v", err)
+	}
+}
+
+func TestAppDiscoveryDoesNotBlockKnownOwners(t *testing.T) {
+	app, fake := newTestApp(t, "acme")
+	if _, err := app.TokenSource("acme").Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the next listing hang, and let an unknown owner trigger it.
+	fake.hang = make(chan struct{})
+	defer close(fake.hang)
+	app.mu.Lock()
+	app.discoveredAt = time.Now().Add(-2 * discoveryInterval)
+	app.mu.Unlock()
+	go app.TokenSource("stranger").Token()
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := app.TokenSource("acme").Token()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a known owner waited for a hung installation listing")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), false},
+		{"pkcs8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), false},
+		{"not pem", []byte("not a key"), true},
+		{"garbage", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}), true},
+	}
+	for _, tt := range tests {
+		if _, err := parsePrivateKey(tt.data); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package githubauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+// Pages of the web flow.
+const (
+	// StartPath is the page the user opens to begin a web flow login. It
+	// names the client the login is for, and ties the login to the
+	// browser that confirms it.
+	StartPath = "/github/login"
+	// CallbackPath is where GitHub redirects the browser at the end of the
+	// web flow.
+	CallbackPath = "/github/callback"
+)
+
+// Cookies of the web flow. The confirm cookie guards the confirmation form
+// against cross-site submission; the state cookie lets only the browser
+// that confirmed a login complete it.
+const (
+	confirmCookie = "github_login_confirm"
+	stateCookie   = "github_login_state"
+)
+
+// loginTimeout is how long a user has to finish a web flow login.
+const loginTimeout = 10 * time.Minute
+
+// Config describes the GitHub OAuth app users sign in to.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// PublicURL is the base URL the server is reached at, under which the
+	// web flow's pages are served. Without it, and the client secret, only
+	// the device flow is available.
+	PublicURL string
+	Scopes    []string
+}
+
+// Done is called when a login finishes, with a token source for the
+// user's token or the reason the login failed.
+type Done func(oauth2.TokenSource, error)
+
+// Login signs users in to GitHub with the OAuth web flow or the device flow
+// and keeps their tokens in a Store, keyed by principal. Tokens that
+// expire are refreshed, and the refreshed token is stored again.
+type Login struct {
+	oauth     *oauth2.Config
+	store     *Store
+	publicURL string
+
+	mu sync.Mutex
+	// pending holds web flow logins by ID.
+	pending map[string]*pendingLogin
+}
+
+type pendingLogin struct {
+	principal string
+	done      Done
+	expiresAt time.Time
+	// nonce is set when the user confirms the login, and kept in the
+	// state cookie of the browser they confirmed it in.
+	nonce string
+}
+
+func NewLogin(config Config, store *Store) *Login {
+	l := &Login{
+		oauth: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			Endpoint:     endpoints.GitHub,
+			Scopes:       config.Scopes,
+		},
+		store:     store,
+		publicURL: strings.TrimSuffix(config.PublicURL, "/"),
+		pending:   make(map[string]*pendingLogin),
+	}
+	if l.publicURL != "" {
+		l.oauth.RedirectURL = l.publicURL + CallbackPath
+	}
+	return l
+}
+
+// WebFlowEnabled reports whether users can sign in through the browser.
+func (l *Login) WebFlowEnabled() bool {
+	return l.oauth.RedirectURL != "" && l.oauth.ClientSecret != ""
+}
+
+// TokenSource returns the stored token of principal.
+func (l *Login) TokenSource(principal string) (oauth2.TokenSource, bool) {
+	token, ok := l.store.Get(principal)
+	if !ok {
+		return nil, false
+	}
+	return l.tokenSource(principal, token), true
+}
+
+// tokenSource refreshes token as needed, storing every new token.
+func (l *Login) tokenSource(principal string, token *oauth2.Token) oauth2.TokenSource {
+	return &storingTokenSource{
+		principal: principal,
+		store:     l.store,
+		source:    l.oauth.TokenSource(context.Background(), token),
+		last:      token.AccessToken,
+	}
+}
+
+// StartWeb begins a web flow login for principal and returns the URL of
+// the page the user must open. done is called when GitHub redirects back.
+func (l *Login) StartWeb(principal string, done Done) (string, error) {
+	if !l.WebFlowEnabled() {
+		return "", errors.New("web flow login is not configured")
+	}
+
+	id, err := randomState()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	for key, p := range l.pending {
+		if now.After(p.expiresAt) {
+			delete(l.pending, key)
+		}
+	}
+	l.pending[id] = &pendingLogin{principal: principal, done: done, expiresAt: now.Add(loginTimeout)}
+	l.mu.Unlock()
+
+	return l.publicURL + StartPath + "?" + url.Values{"login": {id}}.Encode(), nil
+}
+
+// lookup returns the pending login with the given ID.
+func (l *Login) lookup(id string) (*pendingLogin, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, ok := l.pending[id]
+	if !ok || time.Now().After(p.expiresAt) {
+		return nil, false
+	}
+	return p, true
+}
+
+// HandleStart serves the start page. A GET asks the user to confirm that
+// the client the login is for may act as them on GitHub, so a link passed
+// on by someone else cannot silently sign its recipient in for them. The
+// confirmation POSTs back, and is sent on to GitHub.
+func (l *Login) HandleStart(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	if err := r.ParseForm(); err != nil {
+		renderPage(w, http.StatusBadRequest, "The sign in request is malformed.")
+		return
+	}
+
+	id := r.Form.Get("login")
+	pending, ok := l.lookup(id)
+	if !ok {
+		renderPage(w, http.StatusBadRequest, "This sign in link has expired. Start the login again from your MCP client.")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		token, err := randomState()
+		if err != nil {
+			renderPage(w, http.StatusInternalServerError, "The sign in could not be started.")
+			return
+		}
+		l.setCookie(w, confirmCookie, token, StartPath, http.SameSiteStrictMode)
+		renderConfirm(w, confirmation{Action: StartPath, Login: id, Token: token, Principal: pending.principal})
+		return
+	}
+
+	// The confirm cookie is SameSite=Strict, so a form submitted from
+	// another site never carries it.
+	cookie, err := r.Cookie(confirmCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Form.Get("token"))) != 1 {
+		renderPage(w, http.StatusForbidden, "This sign in could not be confirmed. Open the link from your MCP client again.")
+		return
+	}
+
+	nonce, err := randomState()
+	if err != nil {
+		renderPage(w, http.StatusInternalServerError, "The sign in could not be started.")
+		return
+	}
+	l.mu.Lock()
+	pending.nonce = nonce
+	l.mu.Unlock()
+
+	l.clearCookie(w, confirmCookie, StartPath)
+	// GitHub's redirect back is a top-level navigation from another site,
+	// which SameSite=Lax cookies are sent with.
+	l.setCookie(w, stateCookie, nonce, CallbackPath, http.SameSiteLaxMode)
+	http.Redirect(w, r, l.oauth.AuthCodeURL(id+"."+nonce), http.StatusSeeOther)
+}
+
+// HandleCallback completes a web flow login, in the browser that confirmed
+// it.
+func (l *Login) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	l.clearCookie(w, stateCookie, CallbackPath)
+
+	id, nonce, _ := strings.Cut(query.Get("state"), ".")
+	cookie, err := r.Cookie(stateCookie)
+
+	l.mu.Lock()
+	pending, ok := l.pending[id]
+	if ok && (nonce == "" || pending.nonce != nonce) {
+		ok = false
+	}
+	if ok {
+		delete(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		renderPage(w, http.StatusBadRequest, "This sign in link has expired. Start the login again from your MCP client.")
+		return
+	}
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(nonce)) != 1 {
+		pending.done(nil, errors.New("GitHub login was completed in another browser"))
+		renderPage(w, http.StatusForbidden, "This sign in was started in another browser. Start the login again from your MCP client.")
+		return
+	}
+
+	if query.Get("error") != "" {
+		pending.done(nil, errors.New("GitHub login was cancelled: "+query.Get("error_description")))
+		renderPage(w, http.StatusOK, "GitHub sign in was cancelled.")
+		return
+	}
+
+	token, err := l.oauth.Exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		pending.done(nil, err)
+		renderPage(w, http.StatusBadGateway, "GitHub did not issue a token. Start the login again from your MCP client.")
+		return
+	}
+
+	if err := l.complete(pending.principal, token, pending.done); err != nil {
+		renderPage(w, http.StatusInternalServerError, "Your GitHub token could not be saved.")
+		return
+	}
+	renderPage(w, http.StatusOK, "You are signed in to GitHub. You can close this window.")
+}
+
+func (l *Login) setCookie(w http.ResponseWriter, name, value, path string, sameSite http.SameSite) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(loginTimeout / time.Second),
+		Secure:   strings.HasPrefix(l.publicURL, "https://"),
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+func (l *Login) clearCookie(w http.ResponseWriter, name, path string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: path, MaxAge: -1})
+}
+
+// StartDevice begins a device flow login for principal. The user enters
+// the returned user code at the returned verification URI; done is called
+// once they have, or the code expires.
+func (l *Login) StartDevice(ctx context.Context, principal string, done Done) (*oauth2.DeviceAuthResponse, error) {
+	auth, err := l.oauth.DeviceAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		// DeviceAccessToken polls until the code expires.
+		token, err := l.oauth.DeviceAccessToken(context.Background(), auth)
+		if err != nil {
+			done(nil, err)
+			return
+		}
+		l.complete(principal, token, done)
+	}()
+
+	return auth, nil
+}
+
+func (l *Login) complete(principal string, token *oauth2.Token, done Done) error {
+	if err := l.store.Put(principal, token); err != nil {
+		done(nil, err)
+		return err
+	}
+	done(l.tokenSource(principal, token), nil)
+	return nil
+}
+
+// storingTokenSource stores each token its source refreshes.
+type storingTokenSource struct {
+	principal string
+	store     *Store
+	source    oauth2.TokenSource
+
+	mu   sync.Mutex
+	last string
+}
+
+func (s *storingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.last {
+		s.last = token.AccessToken
+		// A token that fails to save still works until the next restart.
+		s.store.Put(s.principal, token)
+	}
+	return token, nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var (
+	page = template.Must(template.New("github-login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>GitHub sign in</title></head>
+<body>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+	confirmPage = template.Must(template.New("github-login-confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>GitHub sign in</title></head>
+<body>
+<p>The MCP client signed in as <strong>{{.Principal}}</strong> asks to act on GitHub as you.</p>
+<p>Continue only if you started this sign in yourself: whoever uses that client will be able to use GitHub with your account.</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="login" value="{{.Login}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Continue to GitHub</button>
+</form>
+</body>
+</html>
+`))
+)
+
+type confirmation struct {
+	Action    string
+	Login     string
+	Token     string
+	Principal string
+}
+
+func renderPage(w http.ResponseWriter, status int, message string) {
+	writePageHeaders(w, status)
+	page.Execute(w, message)
+}
+
+func renderConfirm(w http.ResponseWriter, c confirmation) {
+	writePageHeaders(w, http.StatusOK)
+	confirmPage.Execute(w, c)
+}
+
+// writePageHeaders also forbids framing, so another site cannot trick the
+// user into confirming a login.
+func writePageHeaders(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+}
@@ -0,0 +1,148 @@
+// Package githubauth obtains GitHub credentials: user tokens through OAuth
+// sign in, and installation tokens for a GitHub App.
+package githubauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// Store keeps GitHub tokens keyed by principal, encrypted with AES-256-GCM.
+// With a path, the encrypted tokens are saved to that file and survive
+// restarts; without one they are kept in memory.
+type Store struct {
+	aead cipher.AEAD
+	path string
+
+	mu sync.Mutex
+	// sealed maps a principal to its encrypted token.
+	sealed map[string][]byte
+}
+
+// NewStore opens a store encrypted with a key derived from secret, loading
+// the file at path if it exists.
+func NewStore(secret, path string) (*Store, error) {
+	if secret == "" {
+		return nil, errors.New("token store secret is required")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{aead: aead, path: path, sealed: make(map[string][]byte)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse token store: %w", err)
+	}
+	return s, nil
+}
+
+// Get returns the token stored for principal.
+func (s *Store) Get(principal string) (*oauth2.Token, bool) {
+	s.mu.Lock()
+	sealed, ok := s.sealed[principal]
+	s.mu.Unlock()
+	if !ok || len(sealed) < s.aead.NonceSize() {
+		return nil, false
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	// The principal is authenticated data, so an entry moved to another
+	// principal fails to decrypt.
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(principal))
+	if err != nil {
+		return nil, false
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, false
+	}
+	return &token, true
+}
+
+// Put stores token for principal, replacing any previous one.
+func (s *Store) Put(principal string, token *oauth2.Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, []byte(principal))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sealed[principal] = sealed
+	return s.save()
+}
+
+// Delete forgets the token of principal.
+func (s *Store) Delete(principal string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sealed, principal)
+	return s.save()
+}
+
+// save writes the store to its file, if it has one. The caller must hold
+// s.mu.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.sealed)
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file and rename it, so a crash cannot leave the
+	// store half written.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v62/github"
+)
+
+const (
+	encodingUTF8   = "utf-8"
+	encodingBase64 = "base64"
+)
+
+type getFileContentsArgs struct {
+	repoRef
+	Path string `json:"path" description:"Path of the file or directory; empty for the repository root"`
+	Ref  string `json:"ref" description:"Branch, tag or commit SHA; defaults to the default branch"`
+}
+
+type fileCommitArgs struct {
+	repoRef
+	Path    string `json:"path" description:"Path of the file" jsonschema:"required,minLength=1"`
+	Message string `json:"message" description:"Commit message" jsonschema:"required,minLength=1"`
+	Branch  string `json:"branch" description:"Branch to commit to; defaults to the default branch"`
+}
+
+type createFileArgs struct {
+	fileCommitArgs
+	Content  string `json:"content" description:"New file content" jsonschema:"required"`
+	Encoding string `json:"encoding" description:"Encoding of content; use base64 for binary files" jsonschema:"enum=utf-8|base64,default=utf-8"`
+}
+
+type updateFileArgs struct {
+	createFileArgs
+	SHA string `json:"sha" description:"Blob SHA of the file being replaced; the update fails if the file has changed since" jsonschema:"required,pattern=^[0-9a-f]{40}$"`
+}
+
+type deleteFileArgs struct {
+	fileCommitArgs
+	SHA string `json:"sha" description:"Blob SHA of the file being deleted; the delete fails if the file has changed since" jsonschema:"required,pattern=^[0-9a-f]{40}$"`
+}
+
+// fileContents is a decoded file. Content is plain text when the file is
+// valid UTF-8 and base64 otherwise, as recorded in Encoding.
+type fileContents struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Size     int    `json:"size"`
+	Encoding string `json:"encoding"`
+	Binary   bool   `json:"binary"`
+	Content  string `json:"content"`
+	HTMLURL  string `json:"html_url,omitempty"`
+}
+
+// dirEntry is one entry of a directory listing.
+type dirEntry struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	SHA     string `json:"sha"`
+	Size    int    `json:"size"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+func (h *MCPHandler) contentTools() []Tool {
+	return []Tool{
+		NewTool("get_file_contents", "Get the contents of a file, or the entries of a directory, at a ref", true, h.getFileContents),
+		NewTool("create_file", "Create a file as a new commit on a branch", false, h.createFile),
+		NewTool("update_file", "Replace the contents of a file as a new commit on a branch", false, h.updateFile),
+		NewTool("delete_file", "Delete a file as a new commit on a branch", false, h.deleteFile),
+	}
+}
+
+func (h *MCPHandler) getFileContents(ctx context.Context, args getFileContentsArgs) (*ToolResult, error) {
+	file, dir, err := h.contents(ctx, args.Owner, args.Repo, args.Path, args.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if file != nil {
+		return jsonResult(file)
+	}
+	return jsonResult(map[string]interface{}{
+		"type":    "dir",
+		"path":    args.Path,
+		"entries": dir,
+	})
+}
+
+// contents fetches path at ref and returns either the decoded file or the
+// directory entries.
+func (h *MCPHandler) contents(ctx context.Context, owner, repo, path, ref string) (*fileContents, []dirEntry, error) {
+	file, dir, _, err := h.rest(ctx).Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get contents: %w", err)
+	}
+
+	if file == nil {
+		entries := make([]dirEntry, len(dir))
+		for i, entry := range dir {
+			entries[i] = dirEntry{
+				Type:    entry.GetType(),
+				Name:    entry.GetName(),
+				Path:    entry.GetPath(),
+				SHA:     entry.GetSHA(),
+				Size:    entry.GetSize(),
+				HTMLURL: entry.GetHTMLURL(),
+			}
+		}
+		return nil, entries, nil
+	}
+
+	data, err := h.fileData(ctx, owner, repo, file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &fileContents{
+		Type:    file.GetType(),
+		Name:    file.GetName(),
+		Path:    file.GetPath(),
+		SHA:     file.GetSHA(),
+		Size:    file.GetSize(),
+		HTMLURL: file.GetHTMLURL(),
+	}
+	if isBinary(data) {
+		result.Binary = true
+		result.Encoding = encodingBase64
+		result.Content = base64.StdEncoding.EncodeToString(data)
+	} else {
+		result.Encoding = encodingUTF8
+		result.Content = string(data)
+	}
+	return result, nil, nil
+}
+
+// fileData returns the raw bytes of a file. The contents API leaves files
+// over 1 MB unencoded, so those are fetched as a git blob instead.
+func (h *MCPHandler) fileData(ctx context.Context, owner, repo string, file *github.RepositoryContent) ([]byte, error) {
+	if file.GetEncoding() == "none" {
+		data, _, err := h.rest(ctx).Git.GetBlobRaw(ctx, owner, repo, file.GetSHA())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob: %w", err)
+		}
+		return data, nil
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode contents: %w", err)
+	}
+	return []byte(content), nil
+}
+
+// isBinary reports whether data should not be returned as text: it holds a
+// NUL byte or is not valid UTF-8.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+func (h *MCPHandler) createFile(ctx context.Context, args createFileArgs) (*ToolResult, error) {
+	opts, err := args.options("create_file")
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := h.rest(ctx).Repositories.CreateFile(ctx, args.Owner, args.Repo, args.Path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	return jsonResult(result)
+}
+
+func (h *MCPHandler) updateFile(ctx context.Context, args updateFileArgs) (*ToolResult, error) {
+	opts, err := args.options("update_file")
+	if err != nil {
+		return nil, err
+	}
+	opts.SHA = &args.SHA
+
+	result, _, err := h.rest(ctx).Repositories.UpdateFile(ctx, args.Owner, args.Repo, args.Path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update file: %w", err)
+	}
+
+	return jsonResult(result)
+}
+
+func (h *MCPHandler) deleteFile(ctx context.Context, args deleteFileArgs) (*ToolResult, error) {
+	opts := args.options()
+	opts.SHA = &args.SHA
+
+	result, _, err := h.rest(ctx).Repositories.DeleteFile(ctx, args.Owner, args.Repo, args.Path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return jsonResult(result)
+}
+
+func (a fileCommitArgs) options() *github.RepositoryContentFileOptions {
+	opts := &github.RepositoryContentFileOptions{Message: &a.Message}
+	if a.Branch != "" {
+		opts.Branch = &a.Branch
+	}
+	return opts
+}
+
+// options decodes the new content into commit options for tool.
+func (a createFileArgs) options(tool string) (*github.RepositoryContentFileOptions, error) {
+	opts := a.fileCommitArgs.options()
+
+	if a.Encoding == encodingBase64 {
+		data, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil {
+			return nil, &ArgumentError{Tool: tool, Violations: []Violation{{Path: "content", Message: "is not valid base64"}}}
+		}
+		opts.Content = data
+	} else {
+		opts.Content = []byte(a.Content)
+	}
+	return opts, nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/github-mcp-http/internal/githubauth"
+	"github.com/google/go-github/v62/github"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+// errNoGitHubToken fails GitHub calls from sessions that have no token of
+// their own when the server has no token to fall back to.
+var errNoGitHubToken = errors.New("this session has no GitHub token")
+
+// errNotSignedIn is errNoGitHubToken for servers where sessions can sign in
+// with github_login.
+var errNotSignedIn = fmt.Errorf("%w; sign in with github_login", errNoGitHubToken)
+
+// errNoGitHubUser fails requests for the current user when the server acts
+// as a GitHub App.
+var errNoGitHubUser = errors.New("the server acts as a GitHub App, which has no GitHub user")
+
+// githubClients are the API clients acting as one GitHub identity.
+type githubClients struct {
+	rest    *github.Client
+	graphql *githubv4.Client
+	// app is set for clients acting as a GitHub App, which has no user of
+	// its own: /user endpoints reject installation tokens.
+	app bool
+}
+
+// newGitHubClients returns clients authenticating with tokens from source.
+// All clients share the handler's transport, and with it one connection
+// pool.
+func (h *MCPHandler) newGitHubClients(source oauth2.TokenSource) *githubClients {
+	httpClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: source,
+			Base:   h.transport,
+		},
+	}
+	return &githubClients{
+		rest:    github.NewClient(httpClient),
+		graphql: githubv4.NewClient(httpClient),
+	}
+}
+
+// newAppClients returns clients acting as a GitHub App. Each request is
+// authenticated as the installation on the owner it concerns.
+func (h *MCPHandler) newAppClients(app *githubauth.App) *githubClients {
+	httpClient := &http.Client{Transport: &installationTransport{app: app, base: h.transport}}
+	return &githubClients{
+		rest:    github.NewClient(httpClient),
+		graphql: githubv4.NewClient(httpClient),
+		app:     true,
+	}
+}
+
+// installationTransport takes the owner of a request from its REST path.
+// GraphQL queries and requests such as searches concern no single owner,
+// and get the app's default installation.
+type installationTransport struct {
+	app  *githubauth.App
+	base http.RoundTripper
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := &oauth2.Transport{
+		Source: t.app.TokenSource(requestOwner(req.URL.Path)),
+		Base:   t.base,
+	}
+	return transport.RoundTrip(req)
+}
+
+// requestOwner returns the account a REST API path belongs to, or "" for
+// paths outside /repos, /orgs and /users.
+func requestOwner(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	switch parts[0] {
+	case "repos", "orgs", "users":
+		return parts[1]
+	}
+	return ""
+}
+
+// noGitHubClients returns clients that fail every request with err, for
+// sessions without credentials.
+func noGitHubClients(err error) *githubClients {
+	httpClient := &http.Client{Transport: failingTransport{err: err}}
+	return &githubClients{
+		rest:    github.NewClient(httpClient),
+		graphql: githubv4.NewClient(httpClient),
+	}
+}
+
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
+
+// SetGitHubToken makes the session act on GitHub as the owner of token
+// rather than with the server's token.
+func (h *MCPHandler) SetGitHubToken(session *SessionState, token string) {
+	session.setGitHubClients(h.newGitHubClients(staticToken(token)))
+}
+
+func staticToken(token string) oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+}
+
+// clientsFor returns the clients for the session in ctx, falling back to
+// the server's.
+func (h *MCPHandler) clientsFor(ctx context.Context) *githubClients {
+	if session, ok := SessionFromContext(ctx); ok {
+		return h.sessionClients(session)
+	}
+	return h.fallback
+}
+
+// sessionClients returns the clients session currently acts with, falling
+// back to the server's.
+func (h *MCPHandler) sessionClients(session *SessionState) *githubClients {
+	if clients := session.githubClients(); clients != nil {
+		return clients
+	}
+	return h.fallback
+}
+
+// rest returns the REST client for the session in ctx.
+func (h *MCPHandler) rest(ctx context.Context) *github.Client {
+	return h.clientsFor(ctx).rest
+}
+
+// graphql returns the GraphQL client for the session in ctx.
+func (h *MCPHandler) graphql(ctx context.Context) *githubv4.Client {
+	return h.clientsFor(ctx).graphql
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// rateLimitDocsURL is returned for rate limit errors, which GitHub reports
+// without a documentation link of their own.
+const rateLimitDocsURL = "https://docs.github.com/rest/using-the-rest-api/rate-limits-for-the-rest-api"
+
+// APIError is the structured form of a failed GitHub API call, reported to
+// the model inside an isError tool result.
+type APIError struct {
+	Status           int            `json:"status,omitempty"`
+	Message          string         `json:"message"`
+	DocumentationURL string         `json:"documentation_url,omitempty"`
+	Errors           []github.Error `json:"errors,omitempty"`
+	RateLimitReset   *time.Time     `json:"rate_limit_reset,omitempty"`
+	RetryAfter       string         `json:"retry_after,omitempty"`
+}
+
+// graphQLError marks a failed GraphQL call. GraphQL reports failures in
+// the response body rather than with a status, so only the message is
+// known.
+type graphQLError struct {
+	err error
+}
+
+func (e *graphQLError) Error() string { return e.err.Error() }
+func (e *graphQLError) Unwrap() error { return e.err }
+
+// asAPIError unwraps err into an APIError if it came from the GitHub API.
+func asAPIError(err error) (*APIError, bool) {
+	// A session without credentials is reported as GitHub would report
+	// the unauthenticated request, without making it.
+	if errors.Is(err, errNotSignedIn) {
+		return &APIError{
+			Status:  http.StatusUnauthorized,
+			Message: "This session is not signed in to GitHub; sign in with the github_login tool or reconnect with a token",
+		}, true
+	}
+	if errors.Is(err, errNoGitHubToken) {
+		return &APIError{
+			Status:  http.StatusUnauthorized,
+			Message: "This session has no GitHub token; reconnect with one",
+		}, true
+	}
+
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		reset := rateErr.Rate.Reset.Time
+		return &APIError{
+			Status:           responseStatus(rateErr.Response),
+			Message:          rateErr.Message,
+			DocumentationURL: rateLimitDocsURL,
+			RateLimitReset:   &reset,
+		}, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		apiErr := &APIError{
+			Status:           responseStatus(abuseErr.Response),
+			Message:          abuseErr.Message,
+			DocumentationURL: rateLimitDocsURL,
+		}
+		if abuseErr.RetryAfter != nil {
+			apiErr.RetryAfter = abuseErr.RetryAfter.String()
+		}
+		return apiErr, true
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) {
+		return &APIError{
+			Status:           responseStatus(respErr.Response),
+			Message:          respErr.Message,
+			DocumentationURL: respErr.DocumentationURL,
+			Errors:           respErr.Errors,
+		}, true
+	}
+
+	var gqlErr *graphQLError
+	if errors.As(err, &gqlErr) {
+		return &APIError{Message: err.Error()}, true
+	}
+
+	return nil, false
+}
+
+func responseStatus(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// apiErrorResult reports a GitHub API failure as a tool result so the model
+// can see what went wrong and recover, rather than failing the request.
+func apiErrorResult(apiErr *APIError) *ToolResult {
+	text := fmt.Sprintf("GitHub API error: %s", apiErr.Message)
+	if apiErr.Status != 0 {
+		text = fmt.Sprintf("GitHub API error (%d %s): %s", apiErr.Status, http.StatusText(apiErr.Status), apiErr.Message)
+	}
+
+	data, err := json.Marshal(apiErr)
+	if err == nil {
+		text += "\n" + string(data)
+	}
+
+	return errorResult(text)
+}
+
+// errorResponse turns an error that escaped a method handler into a
+// JSON-RPC error, so callers always get a well-formed response.
+func errorResponse(id interface{}, err error) map[string]interface{} {
+	if apiErr, ok := asAPIError(err); ok {
+		code := codeInternalError
+		if apiErr.Status == http.StatusNotFound {
+			code = codeInvalidParams
+		}
+		return rpcErrorWithData(id, code, apiErr.Message, apiErr)
+	}
+
+	return rpcErrorWithData(id, codeInternalError, "Internal error", map[string]interface{}{
+		"details": err.Error(),
+	})
+}
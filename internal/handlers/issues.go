@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+type createIssueArgs struct {
+	repoRef
+	Title string `json:"title" description:"Issue title" jsonschema:"required"`
+	Body  string `json:"body" description:"Issue body"`
+}
+
+type listIssuesArgs struct {
+	repoRef
+	State     string   `json:"state" description:"Issue state" jsonschema:"enum=open|closed|all,default=open"`
+	Labels    []string `json:"labels" description:"Only issues carrying all of these labels"`
+	Assignee  string   `json:"assignee" description:"Login of the assignee, \"none\" for unassigned or \"*\" for any"`
+	Since     string   `json:"since" description:"Only issues updated at or after this time (ISO 8601)"`
+	Milestone string   `json:"milestone" description:"Milestone number, \"none\" for issues without one or \"*\" for any"`
+	Sort      string   `json:"sort" description:"Sort field" jsonschema:"enum=created|updated|comments,default=created"`
+	Direction string   `json:"direction" description:"Sort direction" jsonschema:"enum=asc|desc,default=desc"`
+	Pagination
+}
+
+type issueRef struct {
+	repoRef
+	IssueNumber int `json:"issue_number" description:"Issue number" jsonschema:"required,minimum=1"`
+}
+
+type getIssueArgs struct {
+	issueRef
+	IncludeComments bool `json:"include_comments" description:"Also return the issue's comments" jsonschema:"default=true"`
+}
+
+type updateIssueArgs struct {
+	issueRef
+	Title       *string   `json:"title" description:"New title"`
+	Body        *string   `json:"body" description:"New body"`
+	State       *string   `json:"state" description:"New state" jsonschema:"enum=open|closed"`
+	StateReason *string   `json:"state_reason" description:"Reason for the state change" jsonschema:"enum=completed|not_planned|reopened"`
+	Labels      *[]string `json:"labels" description:"Labels replacing the current set"`
+	Assignees   *[]string `json:"assignees" description:"Logins replacing the current assignees"`
+	Milestone   *int      `json:"milestone" description:"Milestone number, or 0 to remove the milestone" jsonschema:"minimum=0"`
+}
+
+type addIssueCommentArgs struct {
+	issueRef
+	Body string `json:"body" description:"Comment text" jsonschema:"required,minLength=1"`
+}
+
+type lockIssueArgs struct {
+	issueRef
+	LockReason string `json:"lock_reason" description:"Reason for locking the conversation" jsonschema:"enum=off-topic|too heated|resolved|spam"`
+}
+
+type issueWithComments struct {
+	Issue    *github.Issue          `json:"issue"`
+	Comments []*github.IssueComment `json:"comments,omitempty"`
+	// CommentsTruncated is set when comments stopped at the page cap.
+	CommentsTruncated bool `json:"comments_truncated,omitempty"`
+}
+
+func (h *MCPHandler) issueTools() []Tool {
+	return []Tool{
+		NewTool("list_issues", "List issues in a repository", true, h.listIssues),
+		NewTool("get_issue", "Get an issue and its comments", true, h.getIssue),
+		NewTool("create_issue", "Create a new issue", false, h.createIssue),
+		NewTool("update_issue", "Update an issue's title, body, state, labels, assignees or milestone", false, h.updateIssue),
+		NewTool("add_issue_comment", "Add a comment to an issue", false, h.addIssueComment),
+		NewTool("lock_issue", "Lock an issue's conversation", false, h.lockIssue),
+		NewTool("unlock_issue", "Unlock an issue's conversation", false, h.unlockIssue),
+	}
+}
+
+func (h *MCPHandler) listIssues(ctx context.Context, args listIssuesArgs) (*ToolResult, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:     args.State,
+		Labels:    args.Labels,
+		Assignee:  args.Assignee,
+		Milestone: args.Milestone,
+		Sort:      args.Sort,
+		Direction: args.Direction,
+	}
+
+	if args.Since != "" {
+		since, err := time.Parse(time.RFC3339, args.Since)
+		if err != nil {
+			return nil, &ArgumentError{Tool: "list_issues", Violations: []Violation{{Path: "since", Message: "must be an ISO 8601 timestamp"}}}
+		}
+		opts.Since = since
+	}
+
+	issues, err := fetchPages(ctx, h, args.Pagination, func(listOpts github.ListOptions) ([]*github.Issue, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return h.rest(ctx).Issues.ListByRepo(ctx, args.Owner, args.Repo, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	return jsonResult(issues)
+}
+
+func (h *MCPHandler) getIssue(ctx context.Context, args getIssueArgs) (*ToolResult, error) {
+	issue, _, err := h.rest(ctx).Issues.Get(ctx, args.Owner, args.Repo, args.IssueNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	result := issueWithComments{Issue: issue}
+
+	if args.IncludeComments && issue.GetComments() > 0 {
+		comments, err := fetchPages(ctx, h, Pagination{AutoPaginate: true}, func(opts github.ListOptions) ([]*github.IssueComment, *github.Response, error) {
+			return h.rest(ctx).Issues.ListComments(ctx, args.Owner, args.Repo, args.IssueNumber, &github.IssueListCommentsOptions{
+				ListOptions: opts,
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issue comments: %w", err)
+		}
+		result.Comments = comments.Items
+		result.CommentsTruncated = comments.Truncated
+	}
+
+	return jsonResult(result)
+}
+
+func (h *MCPHandler) createIssue(ctx context.Context, args createIssueArgs) (*ToolResult, error) {
+	issue := &github.IssueRequest{
+		Title: &args.Title,
+		Body:  &args.Body,
+	}
+
+	createdIssue, _, err := h.rest(ctx).Issues.Create(ctx, args.Owner, args.Repo, issue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create issue: %w", err)
+	}
+
+	return jsonResult(createdIssue)
+}
+
+func (h *MCPHandler) updateIssue(ctx context.Context, args updateIssueArgs) (*ToolResult, error) {
+	req := &github.IssueRequest{
+		Title:       args.Title,
+		Body:        args.Body,
+		State:       args.State,
+		StateReason: args.StateReason,
+		Labels:      args.Labels,
+		Assignees:   args.Assignees,
+	}
+
+	// The REST API only clears a milestone when sent an explicit null,
+	// which IssueRequest cannot express, so removal is a separate call.
+	removeMilestone := args.Milestone != nil && *args.Milestone == 0
+	if args.Milestone != nil && !removeMilestone {
+		req.Milestone = args.Milestone
+	}
+
+	issue, _, err := h.rest(ctx).Issues.Edit(ctx, args.Owner, args.Repo, args.IssueNumber, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update issue: %w", err)
+	}
+
+	if removeMilestone {
+		issue, _, err = h.rest(ctx).Issues.RemoveMilestone(ctx, args.Owner, args.Repo, args.IssueNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove milestone: %w", err)
+		}
+	}
+
+	return jsonResult(issue)
+}
+
+func (h *MCPHandler) addIssueComment(ctx context.Context, args addIssueCommentArgs) (*ToolResult, error) {
+	comment, _, err := h.rest(ctx).Issues.CreateComment(ctx, args.Owner, args.Repo, args.IssueNumber, &github.IssueComment{
+		Body: &args.Body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add issue comment: %w", err)
+	}
+
+	return jsonResult(comment)
+}
+
+func (h *MCPHandler) lockIssue(ctx context.Context, args lockIssueArgs) (*ToolResult, error) {
+	_, err := h.rest(ctx).Issues.Lock(ctx, args.Owner, args.Repo, args.IssueNumber, &github.LockIssueOptions{
+		LockReason: args.LockReason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock issue: %w", err)
+	}
+
+	return textResult(fmt.Sprintf("Locked %s/%s#%d", args.Owner, args.Repo, args.IssueNumber)), nil
+}
+
+func (h *MCPHandler) unlockIssue(ctx context.Context, args issueRef) (*ToolResult, error) {
+	_, err := h.rest(ctx).Issues.Unlock(ctx, args.Owner, args.Repo, args.IssueNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock issue: %w", err)
+	}
+
+	return textResult(fmt.Sprintf("Unlocked %s/%s#%d", args.Owner, args.Repo, args.IssueNumber)), nil
+}
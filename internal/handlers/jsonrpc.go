@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// JSON-RPC error codes used by the dispatcher.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+
+	// codeNotInitialized follows the LSP convention for requests that
+	// arrive before the initialize handshake.
+	codeNotInitialized = -32002
+)
+
+func rpcResult(id interface{}, result interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"result":  result,
+		"id":      id,
+	}
+}
+
+// rpcNotification builds a server-initiated notification. Nil params are
+// left out.
+func rpcNotification(method string, params interface{}) map[string]interface{} {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	return msg
+}
+
+// rpcErrorWithData is rpcError with a structured data member.
+func rpcErrorWithData(id interface{}, code int, message string, data interface{}) map[string]interface{} {
+	resp := rpcError(id, code, message)
+	resp["error"].(map[string]interface{})["data"] = data
+	return resp
+}
+
+func rpcError(id interface{}, code int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+		"id": id,
+	}
+}
+
+// maxBatchConcurrency bounds how many messages of one batch run at once.
+const maxBatchConcurrency = 8
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// isRequest reports whether the message expects a response. An explicit
+// "id": null still counts, as JSON-RPC only treats an absent id as a
+// notification.
+func (m *rpcMessage) isRequest() bool {
+	return len(m.ID) > 0
+}
+
+// id returns the request id in a form suitable for echoing back, or nil
+// for notifications.
+func (m *rpcMessage) id() interface{} {
+	if !m.isRequest() {
+		return nil
+	}
+	return m.ID
+}
+
+// parseMessage decodes and validates a single JSON-RPC envelope. On failure
+// it returns the error response to send instead.
+func parseMessage(raw json.RawMessage) (*rpcMessage, map[string]interface{}) {
+	var msg rpcMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || !json.Valid(raw) {
+			return nil, rpcError(nil, codeParseError, "Parse error")
+		}
+		return nil, rpcError(nil, codeInvalidRequest, "Invalid Request")
+	}
+
+	if !validID(msg.ID) {
+		return nil, rpcError(nil, codeInvalidRequest, "Invalid Request: id must be a string, number or null")
+	}
+
+	if msg.JSONRPC != "2.0" {
+		return nil, rpcError(msg.id(), codeInvalidRequest, `Invalid Request: jsonrpc must be "2.0"`)
+	}
+
+	if msg.Method == "" && msg.Result == nil && msg.Error == nil {
+		return nil, rpcError(msg.id(), codeInvalidRequest, "Invalid Request: missing method")
+	}
+
+	return &msg, nil
+}
+
+func validID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return true
+	}
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+func isBatch(raw json.RawMessage) bool {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// processBatch runs each message of a batch concurrently and collects the
+// non-nil responses in request order.
+func (h *MCPHandler) processBatch(ctx context.Context, request json.RawMessage) interface{} {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(request, &batch); err != nil {
+		return rpcError(nil, codeParseError, "Parse error")
+	}
+
+	if len(batch) == 0 {
+		return rpcError(nil, codeInvalidRequest, "Invalid Request: empty batch")
+	}
+
+	responses := make([]interface{}, len(batch))
+	sem := make(chan struct{}, maxBatchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, raw := range batch {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, raw json.RawMessage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i] = h.processBatchMessage(ctx, raw)
+		}(i, raw)
+	}
+	wg.Wait()
+
+	var results []interface{}
+	for _, response := range responses {
+		if response != nil {
+			results = append(results, response)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+	return results
+}
+
+func (h *MCPHandler) processBatchMessage(ctx context.Context, raw json.RawMessage) interface{} {
+	msg, errResp := parseMessage(raw)
+	if errResp != nil {
+		return errResp
+	}
+
+	// The handshake has to complete before anything else can run, so it
+	// cannot share a batch with concurrently dispatched requests.
+	if msg.Method == "initialize" {
+		return rpcError(msg.id(), codeInvalidRequest, "Invalid Request: initialize must not be batched")
+	}
+
+	return h.handleMessage(ctx, msg)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestProcessRPC(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		ping         = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+		notification = `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	)
+
+	tests := []struct {
+		name    string
+		request string
+		// want is the expected response as JSON, or empty for none.
+		want string
+	}{
+		{"request", ping, `{"jsonrpc":"2.0","id":1,"result":{}}`},
+		{"notification", notification, ``},
+		{"parse error", `{"jsonrpc":"2.0","id":1,`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`},
+		{"not an object", `42`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}}`},
+		{"object id", `{"jsonrpc":"2.0","id":{},"method":"ping"}`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request: id must be a string, number or null"}}`},
+		{"wrong version", `{"jsonrpc":"1.0","id":"a","method":"ping"}`, `{"jsonrpc":"2.0","id":"a","error":{"code":-32600,"message":"Invalid Request: jsonrpc must be \"2.0\""}}`},
+		{"missing method", `{"jsonrpc":"2.0","id":2}`, `{"jsonrpc":"2.0","id":2,"error":{"code":-32600,"message":"Invalid Request: missing method"}}`},
+		{"unknown method", `{"jsonrpc":"2.0","id":3,"method":"nope"}`, `{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"Method not found"}}`},
+
+		{"batch", `[` + ping + `,` + notification + `,{"jsonrpc":"2.0","id":"b","method":"ping"}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":"b","result":{}}]`},
+		{"batch of notifications", `[` + notification + `,` + notification + `]`, ``},
+		{"empty batch", `[]`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request: empty batch"}}`},
+		{"batch parse error", `[` + ping + `,`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`},
+		{"batch of invalid messages", `[1,2]`,
+			`[{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request"}}]`},
+		{"batched initialize", `[{"jsonrpc":"2.0","id":1,"method":"initialize"}]`,
+			`[{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"Invalid Request: initialize must not be batched"}}]`},
+	}
+	for _, tt := range tests {
+		response := h.ProcessRPC(context.Background(), json.RawMessage(tt.request))
+		if tt.want == "" {
+			if response != nil {
+				t.Errorf("%s: got a response, want none: %v", tt.name, response)
+			}
+			continue
+		}
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got, want interface{}
+		json.Unmarshal(data, &got)
+		json.Unmarshal([]byte(tt.want), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, data, tt.want)
+		}
+	}
+}
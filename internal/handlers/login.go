@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type githubLoginArgs struct {
+	Flow string `json:"flow,omitempty" description:"How to sign in: web opens a GitHub page in the browser, device asks for a code at github.com/login/device. Defaults to web when the server supports it" jsonschema:"enum=web|device"`
+}
+
+// loginTools sign the calling session in to GitHub. They belong to no
+// toolset, so a session without credentials can always reach them.
+func (h *MCPHandler) loginTools() []Tool {
+	return []Tool{
+		NewTool("github_login", "Sign in to GitHub so this session acts as you. Returns a link to open or a code to enter; GitHub tools work once you have finished signing in", true, h.githubLogin),
+	}
+}
+
+func (h *MCPHandler) githubLogin(ctx context.Context, args githubLoginArgs) (*ToolResult, error) {
+	// Failures are tool errors: the user can usually fix them and try
+	// again.
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return errorResult("GitHub login is only available within a session"), nil
+	}
+	principal := session.Principal()
+	if principal == "" {
+		return errorResult("GitHub login requires an authenticated client, so your token can be kept for you"), nil
+	}
+
+	flow := args.Flow
+	if flow == "" {
+		flow = "device"
+		if h.login.WebFlowEnabled() {
+			flow = "web"
+		}
+	}
+
+	// A failed login leaves the session as it was, and the user can simply
+	// try again.
+	done := func(source oauth2.TokenSource, err error) {
+		if err != nil {
+			return
+		}
+		session.setGitHubClients(h.newGitHubClients(source))
+	}
+
+	switch flow {
+	case "web":
+		url, err := h.login.StartWeb(principal, done)
+		if err != nil {
+			return errorResult(fmt.Sprintf("Failed to start GitHub login: %v", err)), nil
+		}
+		return textResult("Open this URL to sign in to GitHub:\n" + url), nil
+
+	case "device":
+		auth, err := h.login.StartDevice(ctx, principal, done)
+		if err != nil {
+			return errorResult(fmt.Sprintf("Failed to start GitHub login: %v", err)), nil
+		}
+		return textResult(fmt.Sprintf("Go to %s and enter the code %s to sign in to GitHub. The code expires in %d minutes.",
+			auth.VerificationURI, auth.UserCode, int(time.Until(auth.Expiry).Minutes()))), nil
+
+	default:
+		return nil, &ArgumentError{Tool: "github_login", Err: fmt.Errorf("unknown login flow %q", flow)}
+	}
+}
+
+// RestoreGitHubLogin makes the session act as the GitHub user its
+// principal last signed in as, if any, and reports whether it did.
+func (h *MCPHandler) RestoreGitHubLogin(session *SessionState) bool {
+	principal := session.Principal()
+	if h.login == nil || principal == "" {
+		return false
+	}
+
+	source, ok := h.login.TokenSource(principal)
+	if !ok {
+		return false
+	}
+	session.setGitHubClients(h.newGitHubClients(source))
+	return true
+}
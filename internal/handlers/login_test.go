@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github-mcp-http/internal/githubauth"
+)
+
+func TestGitHubLoginFailuresAreToolErrors(t *testing.T) {
+	store, err := githubauth.NewStore("secret", filepath.Join(t.TempDir(), "tokens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a public URL only the device flow is available.
+	login := githubauth.NewLogin(githubauth.Config{ClientID: "client"}, store)
+	h, err := NewMCPHandler(Config{GitHubLogin: login})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedIn := NewSessionState()
+	signedIn.SetPrincipal("api_key:alice")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		flow string
+		want string
+	}{
+		{"no session", context.Background(), "device", "only available within a session"},
+		{"anonymous session", WithSession(context.Background(), NewSessionState()), "device", "requires an authenticated client"},
+		{"web flow not configured", WithSession(context.Background(), signedIn), "web", "Failed to start GitHub login"},
+	}
+	for _, tt := range tests {
+		result, err := h.githubLogin(tt.ctx, githubLoginArgs{Flow: tt.flow})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].Text, tt.want) {
+			t.Errorf("%s: result %+v, want a tool error containing %q", tt.name, result, tt.want)
+		}
+	}
+}
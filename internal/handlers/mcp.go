@@ -3,22 +3,60 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/github-mcp-http/internal/githubauth"
 	"github.com/google/go-github/v62/github"
-	"github.com/shurcooL/githubv4"
-	"golang.org/x/oauth2"
 )
 
+// LatestProtocolVersion is the newest MCP revision this server speaks.
+const LatestProtocolVersion = "2025-03-26"
+
 type MCPHandler struct {
-	client   *github.Client
-	clientV4 *githubv4.Client
-	readOnly bool
+	// transport is shared by the GitHub clients of every session.
+	transport http.RoundTripper
+	// fallback serves sessions without a GitHub token of their own.
+	fallback *githubClients
+	login    *githubauth.Login
+	readOnly atomic.Bool
+	maxPages int
+	tools    *ToolRegistry
+
+	sessionsMu sync.Mutex
+	sessions   map[*SessionState]struct{}
+
+	resourceTemplates []*resourceTemplate
+	subscriptions     *subscriptions
+}
+
+type Config struct {
+	// GitHubToken is used by sessions that have no token of their own. When
+	// empty, such sessions cannot call GitHub.
+	GitHubToken string
+	ReadOnly    bool
+	// MaxPages caps auto-pagination of GitHub list calls. Zero means
+	// DefaultMaxPages.
+	MaxPages int
+	// PollInterval is how often subscribed resources are checked for
+	// changes. Zero means DefaultPollInterval.
+	PollInterval time.Duration
+	// GitHubApp, if set, serves sessions that have no token of their own
+	// instead of GitHubToken.
+	GitHubApp *githubauth.App
+	// GitHubLogin, if set, lets sessions sign in to GitHub with the
+	// github_login tool.
+	GitHubLogin *githubauth.Login
 }
 
 type InitializeResult struct {
-	ServerInfo   ServerInfo   `json:"serverInfo"`
-	Capabilities Capabilities `json:"capabilities"`
+	ProtocolVersion string       `json:"protocolVersion,omitempty"`
+	ServerInfo      ServerInfo   `json:"serverInfo"`
+	Capabilities    Capabilities `json:"capabilities"`
 }
 
 type ServerInfo struct {
@@ -45,35 +83,92 @@ type PromptsCapability struct {
 	ListChanged bool `json:"listChanged"`
 }
 
-func NewMCPHandler(token string, readOnly bool) (*MCPHandler, error) {
-	if token == "" {
-		return nil, fmt.Errorf("GitHub token is required")
+func NewMCPHandler(config Config) (*MCPHandler, error) {
+	maxPages := config.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	h := &MCPHandler{
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+		fallback:  noGitHubClients(errNoGitHubToken),
+		login:     config.GitHubLogin,
+		maxPages:  maxPages,
+		tools:     NewToolRegistry(),
+		sessions:  make(map[*SessionState]struct{}),
+	}
+	h.readOnly.Store(config.ReadOnly)
+	if h.login != nil {
+		h.fallback = noGitHubClients(errNotSignedIn)
+	}
+	if config.GitHubToken != "" {
+		h.fallback = h.newGitHubClients(staticToken(config.GitHubToken))
+	}
+	if config.GitHubApp != nil {
+		h.fallback = h.newAppClients(config.GitHubApp)
+	}
+
+	h.tools.Register("", h.toolsetTools()...)
+	if h.login != nil {
+		h.tools.Register("", h.loginTools()...)
 	}
+	h.tools.Register(ToolsetRepos, h.repositoryTools()...)
+	h.tools.Register(ToolsetRepos, h.contentTools()...)
+	h.tools.Register(ToolsetIssues, h.issueTools()...)
+	h.tools.Register(ToolsetPullRequests, h.pullRequestReadTools()...)
+	h.tools.Register(ToolsetPullRequests, h.pullRequestWriteTools()...)
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
+	h.resourceTemplates = h.repositoryResourceTemplates()
 
-	client := github.NewClient(tc)
-	clientV4 := githubv4.NewClient(tc)
+	pollInterval := config.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	h.subscriptions = newSubscriptions(h, pollInterval)
 
-	return &MCPHandler{
-		client:   client,
-		clientV4: clientV4,
-		readOnly: readOnly,
-	}, nil
+	return h, nil
 }
 
+// Initialize performs the bespoke /connect handshake, which has no separate
+// initialized notification, so the session is ready as soon as it returns.
 func (h *MCPHandler) Initialize(ctx context.Context, clientName, clientVersion string) (*InitializeResult, error) {
+	if session, ok := SessionFromContext(ctx); ok {
+		if session.initialize(LatestProtocolVersion, ClientInfo{Name: clientName, Version: clientVersion}, ClientCapabilities{}) {
+			h.trackSession(session)
+		}
+		session.markReady()
+	}
+
+	return h.initializeResult(ctx, LatestProtocolVersion), nil
+}
+
+// initializeResult describes the server to the session in ctx. Changes are
+// announced only to sessions with a notifier, so only those are offered
+// subscriptions and list change notifications. Tools change with read-only
+// mode and with toolsets, and resource templates and prompts with the
+// toolsets they belong to.
+func (h *MCPHandler) initializeResult(ctx context.Context, protocolVersion string) *InitializeResult {
+	session, ok := SessionFromContext(ctx)
+	notified := ok && session.hasNotifier()
+
+	templatesChange := false
+	for _, t := range h.resourceTemplates {
+		if t.toolset != "" {
+			templatesChange = true
+			break
+		}
+	}
+
 	capabilities := Capabilities{
 		Resources: &ResourcesCapability{
-			Subscribe:   true,
-			ListChanged: true,
+			Subscribe:   notified,
+			ListChanged: notified && templatesChange,
 		},
 		Tools: &ToolsCapability{
-			ListChanged: true,
+			ListChanged: notified,
 		},
 		Prompts: &PromptsCapability{
-			ListChanged: true,
+			ListChanged: notified && promptsToolset != "",
 		},
 	}
 
@@ -83,49 +178,127 @@ func (h *MCPHandler) Initialize(ctx context.Context, clientName, clientVersion s
 	}
 
 	return &InitializeResult{
-		ServerInfo:   serverInfo,
-		Capabilities: capabilities,
-	}, nil
+		ProtocolVersion: protocolVersion,
+		ServerInfo:      serverInfo,
+		Capabilities:    capabilities,
+	}
+}
+
+// ProcessRPC handles a single JSON-RPC message or a batch of them. It
+// returns a nil response when nothing should be sent back, i.e. for
+// notifications and for batches made up only of notifications.
+//
+// Every other outcome, including failures inside a method handler, is
+// reported as a well-formed JSON-RPC response.
+func (h *MCPHandler) ProcessRPC(ctx context.Context, request json.RawMessage) interface{} {
+	if isBatch(request) {
+		return h.processBatch(ctx, request)
+	}
+	return h.processMessage(ctx, request)
+}
+
+func (h *MCPHandler) processMessage(ctx context.Context, request json.RawMessage) interface{} {
+	msg, errResp := parseMessage(request)
+	if errResp != nil {
+		return errResp
+	}
+	return h.handleMessage(ctx, msg)
+}
+
+func (h *MCPHandler) handleMessage(ctx context.Context, msg *rpcMessage) interface{} {
+	// Responses to server-initiated requests carry no method and need no
+	// acknowledgement.
+	if msg.Method == "" {
+		return nil
+	}
+
+	if !msg.isRequest() {
+		h.handleNotification(ctx, msg.Method, msg.Params)
+		return nil
+	}
+
+	response, err := h.dispatch(ctx, msg.Method, msg.Params, msg.id())
+	if err != nil {
+		return errorResponse(msg.id(), err)
+	}
+	return response
+}
+
+func (h *MCPHandler) handleNotification(ctx context.Context, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/initialized":
+		if session, ok := SessionFromContext(ctx); ok {
+			session.markReady()
+		}
+	case "notifications/cancelled":
+		// Requests are short-lived and not tracked, so there is nothing to cancel.
+	}
 }
 
-func (h *MCPHandler) ProcessRPC(ctx context.Context, request json.RawMessage) (interface{}, error) {
-	var rpcReq struct {
-		JSONRPC string          `json:"jsonrpc"`
-		Method  string          `json:"method"`
-		Params  json.RawMessage `json:"params,omitempty"`
-		ID      interface{}     `json:"id"`
+func (h *MCPHandler) dispatch(ctx context.Context, method string, params json.RawMessage, id interface{}) (interface{}, error) {
+	switch method {
+	case "initialize":
+		return h.handleInitialize(ctx, params, id)
+	case "ping":
+		return rpcResult(id, map[string]interface{}{}), nil
 	}
 
-	if err := json.Unmarshal(request, &rpcReq); err != nil {
-		return nil, fmt.Errorf("invalid JSON-RPC request: %w", err)
+	// Until the client confirms initialization with
+	// notifications/initialized, only ping is served.
+	if session, ok := SessionFromContext(ctx); ok && !session.Ready() {
+		return rpcError(id, codeNotInitialized, "Server not initialized"), nil
 	}
 
-	switch rpcReq.Method {
+	switch method {
 	case "resources/list":
-		return h.handleListResources(ctx, rpcReq.ID)
+		return h.handleListResources(ctx, params, id)
 	case "resources/read":
-		return h.handleReadResource(ctx, rpcReq.Params, rpcReq.ID)
+		return h.handleReadResource(ctx, params, id)
+	case "resources/templates/list":
+		return h.handleListResourceTemplates(ctx, params, id)
+	case "resources/subscribe":
+		return h.handleSubscribe(ctx, params, id)
+	case "resources/unsubscribe":
+		return h.handleUnsubscribe(ctx, params, id)
 	case "tools/list":
-		return h.handleListTools(ctx, rpcReq.ID)
+		return h.handleListTools(ctx, params, id)
 	case "tools/call":
-		return h.handleCallTool(ctx, rpcReq.Params, rpcReq.ID)
+		return h.handleCallTool(ctx, params, id)
 	case "prompts/list":
-		return h.handleListPrompts(ctx, rpcReq.ID)
+		return h.handleListPrompts(ctx, params, id)
 	case "prompts/get":
-		return h.handleGetPrompt(ctx, rpcReq.Params, rpcReq.ID)
+		return h.handleGetPrompt(ctx, params, id)
 	default:
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32601,
-				"message": "Method not found",
-			},
-			"id": rpcReq.ID,
-		}, nil
+		return rpcError(id, codeMethodNotFound, "Method not found"), nil
+	}
+}
+
+func (h *MCPHandler) handleInitialize(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	var req struct {
+		ProtocolVersion string             `json:"protocolVersion"`
+		Capabilities    ClientCapabilities `json:"capabilities"`
+		ClientInfo      ClientInfo         `json:"clientInfo"`
+	}
+
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return rpcError(id, codeInvalidParams, "Invalid initialize params"), nil
+		}
+	}
+
+	version := negotiateProtocolVersion(req.ProtocolVersion)
+
+	if session, ok := SessionFromContext(ctx); ok {
+		if !session.initialize(version, req.ClientInfo, req.Capabilities) {
+			return rpcError(id, codeInvalidRequest, "Session already initialized"), nil
+		}
+		h.trackSession(session)
 	}
+
+	return rpcResult(id, h.initializeResult(ctx, version)), nil
 }
 
-func (h *MCPHandler) handleListResources(ctx context.Context, id interface{}) (interface{}, error) {
+func (h *MCPHandler) handleListResources(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
 	resources := []map[string]interface{}{
 		{
 			"uri":         "github://repositories",
@@ -133,21 +306,18 @@ func (h *MCPHandler) handleListResources(ctx context.Context, id interface{}) (i
 			"description": "List of user repositories",
 			"mimeType":    "application/json",
 		},
-		{
+	}
+	// A GitHub App has no user to describe.
+	if !h.clientsFor(ctx).app {
+		resources = append(resources, map[string]interface{}{
 			"uri":         "github://user",
 			"name":        "user",
 			"description": "Current user information",
 			"mimeType":    "application/json",
-		},
+		})
 	}
 
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"resources": resources,
-		},
-		"id": id,
-	}, nil
+	return listResult(id, params, "resources", resources), nil
 }
 
 func (h *MCPHandler) handleReadResource(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
@@ -156,19 +326,17 @@ func (h *MCPHandler) handleReadResource(ctx context.Context, params json.RawMess
 	}
 
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid read resource request: %w", err)
+		return rpcError(id, codeInvalidParams, "Invalid read resource params"), nil
 	}
 
 	switch req.URI {
 	case "github://repositories":
-		repos, _, err := h.client.Repositories.List(ctx, "", &github.RepositoryListOptions{
-			Type:        "all",
-			ListOptions: github.ListOptions{PerPage: 50},
-		})
+		repos, err := h.userRepositories(ctx, Pagination{AutoPaginate: true}, &github.RepositoryListOptions{Type: "all"})
 		if err != nil {
 			return nil, fmt.Errorf("failed to list repositories: %w", err)
 		}
 
+		// The listing is marked truncated when it stopped at the page cap.
 		data, err := json.Marshal(repos)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal repositories: %w", err)
@@ -189,7 +357,11 @@ func (h *MCPHandler) handleReadResource(ctx context.Context, params json.RawMess
 		}, nil
 
 	case "github://user":
-		user, _, err := h.client.Users.Get(ctx, "")
+		if h.clientsFor(ctx).app {
+			return rpcError(id, codeInvalidParams, fmt.Sprintf("%s is not available: %v", req.URI, errNoGitHubUser)), nil
+		}
+
+		user, _, err := h.rest(ctx).Users.Get(ctx, "")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get user: %w", err)
 		}
@@ -214,6 +386,17 @@ func (h *MCPHandler) handleReadResource(ctx context.Context, params json.RawMess
 		}, nil
 
 	default:
+		contents, ok, err := h.readTemplateResource(ctx, req.URI)
+		if errors.Is(err, errInvalidResourceURI) {
+			return rpcError(id, codeInvalidParams, err.Error()), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rpcResult(id, map[string]interface{}{"contents": contents}), nil
+		}
+
 		return map[string]interface{}{
 			"jsonrpc": "2.0",
 			"error": map[string]interface{}{
@@ -225,281 +408,66 @@ func (h *MCPHandler) handleReadResource(ctx context.Context, params json.RawMess
 	}
 }
 
-func (h *MCPHandler) handleListTools(ctx context.Context, id interface{}) (interface{}, error) {
-	tools := []map[string]interface{}{
-		{
-			"name":        "list_repositories",
-			"description": "List user repositories",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"type": map[string]interface{}{
-						"type":        "string",
-						"description": "Repository type (all, owner, member)",
-						"default":     "all",
-					},
-					"sort": map[string]interface{}{
-						"type":        "string",
-						"description": "Sort order (created, updated, pushed, full_name)",
-						"default":     "updated",
-					},
-				},
-			},
-		},
-		{
-			"name":        "get_repository",
-			"description": "Get repository information",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"owner": map[string]interface{}{
-						"type":        "string",
-						"description": "Repository owner",
-					},
-					"repo": map[string]interface{}{
-						"type":        "string",
-						"description": "Repository name",
-					},
-				},
-				"required": []string{"owner", "repo"},
-			},
-		},
-	}
-
-	if !h.readOnly {
-		tools = append(tools, map[string]interface{}{
-			"name":        "create_issue",
-			"description": "Create a new issue",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"owner": map[string]interface{}{
-						"type":        "string",
-						"description": "Repository owner",
-					},
-					"repo": map[string]interface{}{
-						"type":        "string",
-						"description": "Repository name",
-					},
-					"title": map[string]interface{}{
-						"type":        "string",
-						"description": "Issue title",
-					},
-					"body": map[string]interface{}{
-						"type":        "string",
-						"description": "Issue body",
-					},
-				},
-				"required": []string{"owner", "repo", "title"},
-			},
-		})
+func (h *MCPHandler) handleListTools(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	tools := []ToolInfo{}
+	for _, tool := range h.tools.List(h.ReadOnly()) {
+		if !toolsetEnabled(ctx, h.tools.Toolset(tool.Name())) {
+			continue
+		}
+		tools = append(tools, toolInfo(tool))
 	}
 
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"tools": tools,
-		},
-		"id": id,
-	}, nil
+	return listResult(id, params, "tools", tools), nil
 }
 
 func (h *MCPHandler) handleCallTool(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
 	var req struct {
-		Name      string                 `json:"name"`
-		Arguments map[string]interface{} `json:"arguments"`
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
 	}
 
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid call tool request: %w", err)
+		return rpcError(id, codeInvalidParams, "Invalid call tool params"), nil
 	}
 
-	switch req.Name {
-	case "list_repositories":
-		return h.listRepositories(ctx, req.Arguments, id)
-	case "get_repository":
-		return h.getRepository(ctx, req.Arguments, id)
-	case "create_issue":
-		if h.readOnly {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"error": map[string]interface{}{
-					"code":    -32602,
-					"message": "Tool not available in read-only mode",
-				},
-				"id": id,
-			}, nil
-		}
-		return h.createIssue(ctx, req.Arguments, id)
-	default:
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": fmt.Sprintf("Unknown tool: %s", req.Name),
-			},
-			"id": id,
-		}, nil
-	}
-}
-
-func (h *MCPHandler) listRepositories(ctx context.Context, args map[string]interface{}, id interface{}) (interface{}, error) {
-	repoType := "all"
-	sort := "updated"
-
-	if t, ok := args["type"].(string); ok {
-		repoType = t
-	}
-	if s, ok := args["sort"].(string); ok {
-		sort = s
-	}
-
-	repos, _, err := h.client.Repositories.List(ctx, "", &github.RepositoryListOptions{
-		Type:        repoType,
-		Sort:        sort,
-		ListOptions: github.ListOptions{PerPage: 50},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list repositories: %w", err)
-	}
-
-	data, err := json.Marshal(repos)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal repositories: %w", err)
-	}
-
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		"id": id,
-	}, nil
-}
-
-func (h *MCPHandler) getRepository(ctx context.Context, args map[string]interface{}, id interface{}) (interface{}, error) {
-	owner, ok := args["owner"].(string)
+	tool, ok := h.tools.Get(req.Name)
 	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": "owner is required",
-			},
-			"id": id,
-		}, nil
+		return rpcError(id, codeInvalidParams, fmt.Sprintf("Unknown tool: %s", req.Name)), nil
 	}
 
-	repo, ok := args["repo"].(string)
-	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": "repo is required",
-			},
-			"id": id,
-		}, nil
+	if h.ReadOnly() && !tool.ReadOnly() {
+		return rpcError(id, codeInvalidParams, "Tool not available in read-only mode"), nil
 	}
 
-	repository, _, err := h.client.Repositories.Get(ctx, owner, repo)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get repository: %w", err)
+	if toolset := h.tools.Toolset(tool.Name()); !toolsetEnabled(ctx, toolset) {
+		return rpcError(id, codeInvalidParams, fmt.Sprintf("Tool not available: toolset %s is disabled", toolset)), nil
 	}
 
-	data, err := json.Marshal(repository)
+	result, err := tool.Call(ctx, req.Arguments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal repository: %w", err)
+		var argErr *ArgumentError
+		if errors.As(err, &argErr) {
+			if argErr.Violations != nil {
+				return rpcErrorWithData(id, codeInvalidParams, argErr.Error(), map[string]interface{}{
+					"violations": argErr.Violations,
+				}), nil
+			}
+			return rpcError(id, codeInvalidParams, argErr.Error()), nil
+		}
+		if apiErr, ok := asAPIError(err); ok {
+			return rpcResult(id, apiErrorResult(apiErr)), nil
+		}
+		return nil, err
 	}
 
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		"id": id,
-	}, nil
+	return rpcResult(id, result), nil
 }
 
-func (h *MCPHandler) createIssue(ctx context.Context, args map[string]interface{}, id interface{}) (interface{}, error) {
-	owner, ok := args["owner"].(string)
-	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": "owner is required",
-			},
-			"id": id,
-		}, nil
-	}
-
-	repo, ok := args["repo"].(string)
-	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": "repo is required",
-			},
-			"id": id,
-		}, nil
+func (h *MCPHandler) handleListPrompts(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	if !toolsetEnabled(ctx, promptsToolset) {
+		return listResult(id, params, "prompts", []map[string]interface{}{}), nil
 	}
 
-	title, ok := args["title"].(string)
-	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32602,
-				"message": "title is required",
-			},
-			"id": id,
-		}, nil
-	}
-
-	body, _ := args["body"].(string)
-
-	issue := &github.IssueRequest{
-		Title: &title,
-		Body:  &body,
-	}
-
-	createdIssue, _, err := h.client.Issues.Create(ctx, owner, repo, issue)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create issue: %w", err)
-	}
-
-	data, err := json.Marshal(createdIssue)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal issue: %w", err)
-	}
-
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": string(data),
-				},
-			},
-		},
-		"id": id,
-	}, nil
-}
-
-func (h *MCPHandler) handleListPrompts(ctx context.Context, id interface{}) (interface{}, error) {
 	prompts := []map[string]interface{}{
 		{
 			"name":        "analyze_repository",
@@ -519,13 +487,7 @@ func (h *MCPHandler) handleListPrompts(ctx context.Context, id interface{}) (int
 		},
 	}
 
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"prompts": prompts,
-		},
-		"id": id,
-	}, nil
+	return listResult(id, params, "prompts", prompts), nil
 }
 
 func (h *MCPHandler) handleGetPrompt(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
@@ -535,11 +497,15 @@ func (h *MCPHandler) handleGetPrompt(ctx context.Context, params json.RawMessage
 	}
 
 	if err := json.Unmarshal(params, &req); err != nil {
-		return nil, fmt.Errorf("invalid get prompt request: %w", err)
+		return rpcError(id, codeInvalidParams, "Invalid get prompt params"), nil
 	}
 
 	switch req.Name {
 	case "analyze_repository":
+		if !toolsetEnabled(ctx, promptsToolset) {
+			return rpcError(id, codeInvalidParams, fmt.Sprintf("Prompt not available: toolset %s is disabled", promptsToolset)), nil
+		}
+
 		owner := req.Arguments["owner"]
 		repo := req.Arguments["repo"]
 
@@ -581,4 +547,4 @@ Please focus on actionable insights and recommendations.`, owner, repo)
 			"id": id,
 		}, nil
 	}
-}
\ No newline at end of file
+}
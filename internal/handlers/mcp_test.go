@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInitializeCapabilities(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notified := NewSessionState()
+	notified.SetNotifier(func(interface{}) {})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"session with a notifier", WithSession(context.Background(), notified), true},
+		{"session without a notifier", WithSession(context.Background(), NewSessionState()), false},
+		{"no session", context.Background(), false},
+	}
+	for _, tt := range tests {
+		caps := h.initializeResult(tt.ctx, LatestProtocolVersion).Capabilities
+		got := map[string]bool{
+			"resources.subscribe":   caps.Resources.Subscribe,
+			"resources.listChanged": caps.Resources.ListChanged,
+			"tools.listChanged":     caps.Tools.ListChanged,
+			"prompts.listChanged":   caps.Prompts.ListChanged,
+		}
+		for name, value := range got {
+			if value != tt.want {
+				t.Errorf("%s: %s = %v, want %v", tt.name, name, value, tt.want)
+			}
+		}
+	}
+}
+
+func TestInitializationHandshake(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewSessionState()
+	ctx := WithSession(context.Background(), session)
+
+	steps := []struct {
+		message string
+		want    string
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, `"code":-32002`},
+		{`{"jsonrpc":"2.0","method":"notifications/initialized"}`, ``},
+		{`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, `"code":-32002`},
+		{`{"jsonrpc":"2.0","id":3,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, `"protocolVersion"`},
+		{`{"jsonrpc":"2.0","id":4,"method":"tools/list"}`, `"code":-32002`},
+		{`{"jsonrpc":"2.0","id":5,"method":"ping"}`, `"result":{}`},
+		{`{"jsonrpc":"2.0","method":"notifications/initialized"}`, ``},
+		{`{"jsonrpc":"2.0","id":6,"method":"tools/list"}`, `"tools"`},
+		{`{"jsonrpc":"2.0","id":7,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, `Session already initialized`},
+	}
+	for _, step := range steps {
+		resp := h.ProcessRPC(ctx, json.RawMessage(step.message))
+		if step.want == "" {
+			if resp != nil {
+				t.Errorf("%s: unexpected response %v", step.message, resp)
+			}
+			continue
+		}
+		data, _ := json.Marshal(resp)
+		if !strings.Contains(string(data), step.want) {
+			t.Errorf("%s: %s does not contain %s", step.message, data, step.want)
+		}
+	}
+}
+
+func TestBespokeInitializeIsReady(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewSessionState()
+	if _, err := h.Initialize(WithSession(context.Background(), session), "client", "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if !session.Ready() {
+		t.Error("session not ready after the /connect handshake")
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/google/go-github/v62/github"
+)
+
+const (
+	// DefaultMaxPages caps how many GitHub pages one auto-paginated call
+	// may fetch when the handler is not configured otherwise.
+	DefaultMaxPages = 10
+
+	// listPageSize is the number of entries per MCP cursor page for
+	// tools/list, resources/list and prompts/list.
+	listPageSize = 50
+
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+// Pagination is embedded in the arguments of every list tool.
+type Pagination struct {
+	Page         int  `json:"page" description:"Page number to start from (1-based)" jsonschema:"minimum=1,default=1"`
+	PerPage      int  `json:"per_page" description:"Results per page (default 30, or 100 when fetching several pages)" jsonschema:"minimum=1,maximum=100"`
+	Limit        int  `json:"limit" description:"Maximum number of results to return; pages are fetched automatically until it is reached" jsonschema:"minimum=1"`
+	AutoPaginate bool `json:"auto_paginate" description:"Keep fetching pages until the results are exhausted or the server's page cap is reached"`
+}
+
+// listPage is the result of a paginated GitHub list call.
+type listPage[T any] struct {
+	Items []T `json:"items"`
+	// NextPage is the page to request to continue, or 0 when there are no
+	// more results.
+	NextPage int `json:"next_page,omitempty"`
+	// Truncated is set when fetching stopped at the server's page cap.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// fetchPages calls fetch for successive pages as directed by p, following
+// Response.NextPage until the results, the limit or h.maxPages run out.
+func fetchPages[T any](ctx context.Context, h *MCPHandler, p Pagination, fetch func(github.ListOptions) ([]T, *github.Response, error)) (*listPage[T], error) {
+	auto := p.AutoPaginate || p.Limit > 0
+
+	opts := github.ListOptions{Page: p.Page, PerPage: p.PerPage}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PerPage < 1 || opts.PerPage > maxPerPage {
+		opts.PerPage = defaultPerPage
+		if auto {
+			opts.PerPage = maxPerPage
+		}
+	}
+	if p.Limit > 0 && p.Limit < opts.PerPage {
+		opts.PerPage = p.Limit
+	}
+
+	result := &listPage[T]{Items: []T{}}
+
+	for pages := 1; ; pages++ {
+		items, resp, err := fetch(opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, items...)
+		result.NextPage = resp.NextPage
+
+		if p.Limit > 0 && len(result.Items) >= p.Limit {
+			result.Items = result.Items[:p.Limit]
+			return result, nil
+		}
+		if !auto || resp.NextPage == 0 {
+			return result, nil
+		}
+		if pages >= h.maxPages {
+			result.Truncated = true
+			return result, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageOf returns one cursor page of items and the cursor for the next page,
+// which is empty on the last page. Cursors are opaque to clients.
+func pageOf[T any](items []T, cursor string) ([]T, string, error) {
+	offset := 0
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", errInvalidCursor
+		}
+		offset, err = strconv.Atoi(string(raw))
+		if err != nil || offset < 0 || offset > len(items) {
+			return nil, "", errInvalidCursor
+		}
+	}
+
+	end := offset + listPageSize
+	if end >= len(items) {
+		return items[offset:], "", nil
+	}
+
+	next := base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	return items[offset:end], next, nil
+}
+
+// listResult builds the response to an MCP list method, returning the
+// page of items selected by the request's cursor under key.
+func listResult[T any](id interface{}, params json.RawMessage, key string, items []T) map[string]interface{} {
+	var req struct {
+		Cursor string `json:"cursor"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return rpcError(id, codeInvalidParams, "Invalid list params")
+		}
+	}
+
+	page, next, err := pageOf(items, req.Cursor)
+	if err != nil {
+		return rpcError(id, codeInvalidParams, "Invalid cursor")
+	}
+
+	result := map[string]interface{}{key: page}
+	if next != "" {
+		result["nextCursor"] = next
+	}
+	return rpcResult(id, result)
+}
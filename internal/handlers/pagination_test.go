@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTruncationIsReported(t *testing.T) {
+	h := newTestHandler(t, Config{GitHubToken: "token", MaxPages: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every list has another page.
+		w.Header().Set("Link", `<https://api.github.com`+r.URL.Path+`?page=2>; rel="next"`)
+		switch r.URL.Path {
+		case "/user/repos":
+			w.Write([]byte(`[{"id":1}]`))
+		case "/repos/octo/hello/issues/1":
+			w.Write([]byte(`{"number":1,"comments":200}`))
+		case "/repos/octo/hello/issues/1/comments":
+			w.Write([]byte(`[{"id":1}]`))
+		case "/repos/octo/hello/pulls/1":
+			w.Write([]byte(`{"number":1,"head":{"sha":"abc"}}`))
+		case "/repos/octo/hello/commits/abc/status":
+			w.Write([]byte(`{"state":"success","total_count":0}`))
+		case "/repos/octo/hello/commits/abc/check-runs":
+			w.Write([]byte(`{"total_count":250,"check_runs":[{"name":"build"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() (interface{}, error)
+		want []string
+	}{
+		{
+			"repositories resource",
+			func() (interface{}, error) {
+				return h.handleReadResource(ctx, json.RawMessage(`{"uri":"github://repositories"}`), 1)
+			},
+			[]string{`\"truncated\":true`},
+		},
+		{
+			"issue comments",
+			func() (interface{}, error) {
+				return h.handleCallTool(ctx, json.RawMessage(`{"name":"get_issue","arguments":{"owner":"octo","repo":"hello","issue_number":1}}`), 1)
+			},
+			[]string{`\"comments_truncated\":true`},
+		},
+		{
+			"check runs",
+			func() (interface{}, error) {
+				return h.handleCallTool(ctx, json.RawMessage(`{"name":"get_pull_request_status","arguments":{"owner":"octo","repo":"hello","pull_number":1}}`), 1)
+			},
+			[]string{`\"check_runs_truncated\":true`, `\"total_check_runs\":250`},
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.call()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		data, _ := json.Marshal(resp)
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: %s does not contain %s", tt.name, data, want)
+			}
+		}
+	}
+}
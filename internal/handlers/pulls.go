@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/shurcooL/githubv4"
+)
+
+type pullRef struct {
+	repoRef
+	PullNumber int `json:"pull_number" description:"Pull request number" jsonschema:"required,minimum=1"`
+}
+
+type listPullRequestsArgs struct {
+	repoRef
+	State     string `json:"state" description:"Pull request state" jsonschema:"enum=open|closed|all,default=open"`
+	Head      string `json:"head" description:"Filter by head user or organization and branch, as user:ref-name"`
+	Base      string `json:"base" description:"Filter by base branch name"`
+	Sort      string `json:"sort" description:"Sort field" jsonschema:"enum=created|updated|popularity|long-running,default=created"`
+	Direction string `json:"direction" description:"Sort direction" jsonschema:"enum=asc|desc,default=desc"`
+	Pagination
+}
+
+type listPullRequestPagesArgs struct {
+	pullRef
+	Pagination
+}
+
+type createPullRequestArgs struct {
+	repoRef
+	Title               string `json:"title" description:"Pull request title" jsonschema:"required,minLength=1"`
+	Head                string `json:"head" description:"Branch containing the changes; use user:branch for cross-repository pull requests" jsonschema:"required"`
+	Base                string `json:"base" description:"Branch the changes should be merged into" jsonschema:"required"`
+	Body                string `json:"body" description:"Pull request description"`
+	Draft               bool   `json:"draft" description:"Open the pull request as a draft"`
+	MaintainerCanModify *bool  `json:"maintainer_can_modify" description:"Allow maintainers to push to the head branch"`
+}
+
+type updatePullRequestArgs struct {
+	pullRef
+	Title *string `json:"title" description:"New title"`
+	Body  *string `json:"body" description:"New description"`
+	State *string `json:"state" description:"New state" jsonschema:"enum=open|closed"`
+	Base  *string `json:"base" description:"New base branch"`
+	Draft *bool   `json:"draft" description:"Convert to a draft (true) or mark ready for review (false)"`
+}
+
+type requestReviewersArgs struct {
+	pullRef
+	Reviewers     []string `json:"reviewers" description:"Logins of users to request a review from"`
+	TeamReviewers []string `json:"team_reviewers" description:"Slugs of teams to request a review from"`
+}
+
+type reviewComment struct {
+	Path      string  `json:"path" description:"Path of the file to comment on" jsonschema:"required"`
+	Body      string  `json:"body" description:"Comment text" jsonschema:"required,minLength=1"`
+	Line      int     `json:"line" description:"Line in the diff the comment applies to (last line for multi-line comments)" jsonschema:"required,minimum=1"`
+	Side      string  `json:"side" description:"Side of the diff the line is on" jsonschema:"enum=LEFT|RIGHT,default=RIGHT"`
+	StartLine *int    `json:"start_line" description:"First line of a multi-line comment" jsonschema:"minimum=1"`
+	StartSide *string `json:"start_side" description:"Side of the diff the first line is on" jsonschema:"enum=LEFT|RIGHT"`
+}
+
+type createPullRequestReviewArgs struct {
+	pullRef
+	Event    string          `json:"event" description:"Review action" jsonschema:"required,enum=APPROVE|REQUEST_CHANGES|COMMENT"`
+	Body     string          `json:"body" description:"Review summary; required for REQUEST_CHANGES and COMMENT"`
+	CommitID string          `json:"commit_id" description:"SHA of the commit being reviewed; defaults to the pull request's head"`
+	Comments []reviewComment `json:"comments" description:"Inline comments on specific diff lines"`
+}
+
+type mergePullRequestArgs struct {
+	pullRef
+	MergeMethod   string `json:"merge_method" description:"How to merge" jsonschema:"enum=merge|squash|rebase,default=merge"`
+	CommitTitle   string `json:"commit_title" description:"Title of the merge commit"`
+	CommitMessage string `json:"commit_message" description:"Extra detail for the merge commit"`
+	SHA           string `json:"sha" description:"Head SHA the pull request must still be at for the merge to proceed" jsonschema:"pattern=^[0-9a-f]{40}$"`
+}
+
+// checkSummary condenses the commit statuses and check runs for a SHA.
+type checkSummary struct {
+	SHA           string           `json:"sha"`
+	State         string           `json:"state"`
+	TotalStatuses int              `json:"total_statuses"`
+	Statuses      []statusSummary  `json:"statuses"`
+	TotalChecks   int              `json:"total_check_runs"`
+	CheckRuns     []checkRunResult `json:"check_runs"`
+	// CheckRunsTruncated is set when check runs stopped at the page cap,
+	// so CheckRuns and Conclusions cover only some of TotalChecks.
+	CheckRunsTruncated bool           `json:"check_runs_truncated,omitempty"`
+	Conclusions        map[string]int `json:"conclusions"`
+}
+
+type statusSummary struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+type checkRunResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+	HTMLURL    string `json:"html_url,omitempty"`
+}
+
+func (h *MCPHandler) pullRequestReadTools() []Tool {
+	return []Tool{
+		NewTool("list_pull_requests", "List pull requests in a repository", true, h.listPullRequests),
+		NewTool("get_pull_request", "Get a pull request", true, h.getPullRequest),
+		NewTool("list_pull_request_files", "List the files changed by a pull request, with patch hunks", true, h.listPullRequestFiles),
+		NewTool("get_pull_request_diff", "Get the unified diff of a pull request", true, h.getPullRequestDiff),
+		NewTool("list_pull_request_review_comments", "List the inline review comments on a pull request", true, h.listPullRequestReviewComments),
+		NewTool("list_pull_request_reviews", "List the reviews on a pull request", true, h.listPullRequestReviews),
+		NewTool("get_pull_request_status", "Summarize the combined status and check runs for a pull request's head commit", true, h.getPullRequestStatus),
+	}
+}
+
+func (h *MCPHandler) pullRequestWriteTools() []Tool {
+	return []Tool{
+		NewTool("create_pull_request", "Open a pull request from head to base", false, h.createPullRequest),
+		NewTool("update_pull_request", "Update a pull request's title, body, state, base or draft status", false, h.updatePullRequest),
+		NewTool("request_reviewers", "Request reviews on a pull request from users or teams", false, h.requestReviewers),
+		NewTool("create_pull_request_review", "Submit a review on a pull request, optionally with inline comments", false, h.createPullRequestReview),
+		NewTool("merge_pull_request", "Merge a pull request", false, h.mergePullRequest),
+	}
+}
+
+func (h *MCPHandler) listPullRequests(ctx context.Context, args listPullRequestsArgs) (*ToolResult, error) {
+	opts := &github.PullRequestListOptions{
+		State:     args.State,
+		Head:      args.Head,
+		Base:      args.Base,
+		Sort:      args.Sort,
+		Direction: args.Direction,
+	}
+
+	pulls, err := fetchPages(ctx, h, args.Pagination, func(listOpts github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return h.rest(ctx).PullRequests.List(ctx, args.Owner, args.Repo, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	return jsonResult(pulls)
+}
+
+func (h *MCPHandler) getPullRequest(ctx context.Context, args pullRef) (*ToolResult, error) {
+	pull, _, err := h.rest(ctx).PullRequests.Get(ctx, args.Owner, args.Repo, args.PullNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	return jsonResult(pull)
+}
+
+func (h *MCPHandler) listPullRequestFiles(ctx context.Context, args listPullRequestPagesArgs) (*ToolResult, error) {
+	files, err := fetchPages(ctx, h, args.Pagination, func(opts github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+		return h.rest(ctx).PullRequests.ListFiles(ctx, args.Owner, args.Repo, args.PullNumber, &opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull request files: %w", err)
+	}
+
+	return jsonResult(files)
+}
+
+func (h *MCPHandler) getPullRequestDiff(ctx context.Context, args pullRef) (*ToolResult, error) {
+	diff, _, err := h.rest(ctx).PullRequests.GetRaw(ctx, args.Owner, args.Repo, args.PullNumber, github.RawOptions{Type: github.Diff})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request diff: %w", err)
+	}
+
+	return textResult(diff), nil
+}
+
+func (h *MCPHandler) listPullRequestReviewComments(ctx context.Context, args listPullRequestPagesArgs) (*ToolResult, error) {
+	comments, err := fetchPages(ctx, h, args.Pagination, func(opts github.ListOptions) ([]*github.PullRequestComment, *github.Response, error) {
+		return h.rest(ctx).PullRequests.ListComments(ctx, args.Owner, args.Repo, args.PullNumber, &github.PullRequestListCommentsOptions{
+			ListOptions: opts,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review comments: %w", err)
+	}
+
+	return jsonResult(comments)
+}
+
+func (h *MCPHandler) listPullRequestReviews(ctx context.Context, args listPullRequestPagesArgs) (*ToolResult, error) {
+	reviews, err := fetchPages(ctx, h, args.Pagination, func(opts github.ListOptions) ([]*github.PullRequestReview, *github.Response, error) {
+		return h.rest(ctx).PullRequests.ListReviews(ctx, args.Owner, args.Repo, args.PullNumber, &opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	return jsonResult(reviews)
+}
+
+func (h *MCPHandler) getPullRequestStatus(ctx context.Context, args pullRef) (*ToolResult, error) {
+	pull, _, err := h.rest(ctx).PullRequests.Get(ctx, args.Owner, args.Repo, args.PullNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	sha := pull.GetHead().GetSHA()
+
+	combined, _, err := h.rest(ctx).Repositories.GetCombinedStatus(ctx, args.Owner, args.Repo, sha, &github.ListOptions{PerPage: maxPerPage})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get combined status: %w", err)
+	}
+
+	var totalChecks int
+	runs, err := fetchPages(ctx, h, Pagination{AutoPaginate: true}, func(opts github.ListOptions) ([]*github.CheckRun, *github.Response, error) {
+		result, resp, err := h.rest(ctx).Checks.ListCheckRunsForRef(ctx, args.Owner, args.Repo, sha, &github.ListCheckRunsOptions{
+			ListOptions: opts,
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		totalChecks = result.GetTotal()
+		return result.CheckRuns, resp, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list check runs: %w", err)
+	}
+
+	summary := checkSummary{
+		SHA:           sha,
+		State:         combined.GetState(),
+		TotalStatuses: combined.GetTotalCount(),
+		Statuses:      []statusSummary{},
+		TotalChecks:   len(runs.Items),
+		CheckRuns:     []checkRunResult{},
+		Conclusions:   map[string]int{},
+	}
+	if runs.Truncated {
+		summary.TotalChecks = totalChecks
+		summary.CheckRunsTruncated = true
+	}
+
+	for _, status := range combined.Statuses {
+		summary.Statuses = append(summary.Statuses, statusSummary{
+			Context:     status.GetContext(),
+			State:       status.GetState(),
+			Description: status.GetDescription(),
+			TargetURL:   status.GetTargetURL(),
+		})
+	}
+
+	for _, run := range runs.Items {
+		summary.CheckRuns = append(summary.CheckRuns, checkRunResult{
+			Name:       run.GetName(),
+			Status:     run.GetStatus(),
+			Conclusion: run.GetConclusion(),
+			HTMLURL:    run.GetHTMLURL(),
+		})
+
+		// Runs still in progress have no conclusion yet; count them by status.
+		key := run.GetConclusion()
+		if key == "" {
+			key = run.GetStatus()
+		}
+		summary.Conclusions[key]++
+	}
+
+	return jsonResult(summary)
+}
+
+func (h *MCPHandler) createPullRequest(ctx context.Context, args createPullRequestArgs) (*ToolResult, error) {
+	pull, _, err := h.rest(ctx).PullRequests.Create(ctx, args.Owner, args.Repo, &github.NewPullRequest{
+		Title:               &args.Title,
+		Head:                &args.Head,
+		Base:                &args.Base,
+		Body:                &args.Body,
+		Draft:               &args.Draft,
+		MaintainerCanModify: args.MaintainerCanModify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+
+	return jsonResult(pull)
+}
+
+func (h *MCPHandler) updatePullRequest(ctx context.Context, args updatePullRequestArgs) (*ToolResult, error) {
+	var pull *github.PullRequest
+	var err error
+
+	if args.Title != nil || args.Body != nil || args.State != nil || args.Base != nil {
+		update := &github.PullRequest{
+			Title: args.Title,
+			Body:  args.Body,
+			State: args.State,
+		}
+		if args.Base != nil {
+			update.Base = &github.PullRequestBranch{Ref: args.Base}
+		}
+
+		pull, _, err = h.rest(ctx).PullRequests.Edit(ctx, args.Owner, args.Repo, args.PullNumber, update)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update pull request: %w", err)
+		}
+	}
+
+	// Draft status can only be changed through GraphQL.
+	if args.Draft != nil {
+		if pull == nil {
+			pull, _, err = h.rest(ctx).PullRequests.Get(ctx, args.Owner, args.Repo, args.PullNumber)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pull request: %w", err)
+			}
+		}
+
+		if pull.GetDraft() != *args.Draft {
+			if err := h.setPullRequestDraft(ctx, pull.GetNodeID(), *args.Draft); err != nil {
+				return nil, err
+			}
+			pull.Draft = args.Draft
+		}
+	}
+
+	if pull == nil {
+		return nil, &ArgumentError{Tool: "update_pull_request", Err: fmt.Errorf("nothing to update")}
+	}
+
+	return jsonResult(pull)
+}
+
+func (h *MCPHandler) setPullRequestDraft(ctx context.Context, nodeID string, draft bool) error {
+	if draft {
+		var mutation struct {
+			ConvertPullRequestToDraft struct {
+				ClientMutationID githubv4.String
+			} `graphql:"convertPullRequestToDraft(input: $input)"`
+		}
+		input := githubv4.ConvertPullRequestToDraftInput{PullRequestID: githubv4.ID(nodeID)}
+		if err := h.graphql(ctx).Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to convert pull request to draft: %w", &graphQLError{err})
+		}
+		return nil
+	}
+
+	var mutation struct {
+		MarkPullRequestReadyForReview struct {
+			ClientMutationID githubv4.String
+		} `graphql:"markPullRequestReadyForReview(input: $input)"`
+	}
+	input := githubv4.MarkPullRequestReadyForReviewInput{PullRequestID: githubv4.ID(nodeID)}
+	if err := h.graphql(ctx).Mutate(ctx, &mutation, input, nil); err != nil {
+		return fmt.Errorf("failed to mark pull request ready for review: %w", &graphQLError{err})
+	}
+	return nil
+}
+
+func (h *MCPHandler) requestReviewers(ctx context.Context, args requestReviewersArgs) (*ToolResult, error) {
+	if len(args.Reviewers) == 0 && len(args.TeamReviewers) == 0 {
+		return nil, &ArgumentError{Tool: "request_reviewers", Err: fmt.Errorf("at least one of reviewers or team_reviewers is required")}
+	}
+
+	pull, _, err := h.rest(ctx).PullRequests.RequestReviewers(ctx, args.Owner, args.Repo, args.PullNumber, github.ReviewersRequest{
+		Reviewers:     args.Reviewers,
+		TeamReviewers: args.TeamReviewers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request reviewers: %w", err)
+	}
+
+	return jsonResult(pull)
+}
+
+func (h *MCPHandler) createPullRequestReview(ctx context.Context, args createPullRequestReviewArgs) (*ToolResult, error) {
+	if args.Event != "APPROVE" && args.Body == "" && len(args.Comments) == 0 {
+		return nil, &ArgumentError{Tool: "create_pull_request_review", Err: fmt.Errorf("body is required for %s reviews", args.Event)}
+	}
+
+	review := &github.PullRequestReviewRequest{
+		Event: &args.Event,
+	}
+	if args.Body != "" {
+		review.Body = &args.Body
+	}
+	if args.CommitID != "" {
+		review.CommitID = &args.CommitID
+	}
+
+	for _, c := range args.Comments {
+		side := c.Side
+		if side == "" {
+			side = "RIGHT"
+		}
+		review.Comments = append(review.Comments, &github.DraftReviewComment{
+			Path:      &c.Path,
+			Body:      &c.Body,
+			Line:      &c.Line,
+			Side:      &side,
+			StartLine: c.StartLine,
+			StartSide: c.StartSide,
+		})
+	}
+
+	created, _, err := h.rest(ctx).PullRequests.CreateReview(ctx, args.Owner, args.Repo, args.PullNumber, review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+
+	return jsonResult(created)
+}
+
+func (h *MCPHandler) mergePullRequest(ctx context.Context, args mergePullRequestArgs) (*ToolResult, error) {
+	result, _, err := h.rest(ctx).PullRequests.Merge(ctx, args.Owner, args.Repo, args.PullNumber, args.CommitMessage, &github.PullRequestOptions{
+		CommitTitle: args.CommitTitle,
+		SHA:         args.SHA,
+		MergeMethod: args.MergeMethod,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge pull request: %w", err)
+	}
+
+	return jsonResult(result)
+}
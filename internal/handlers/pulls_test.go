@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUpdatePullRequestDraftErrors(t *testing.T) {
+	h := newTestHandler(t, Config{GitHubToken: "token"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/hello/pulls/1":
+			w.Write([]byte(`{"number":1,"draft":false,"node_id":"PR_1"}`))
+		case "/graphql":
+			w.Write([]byte(`{"data":null,"errors":[{"message":"Resource not accessible by integration"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	params := json.RawMessage(`{"name":"update_pull_request","arguments":{"owner":"octo","repo":"hello","pull_number":1,"draft":true}}`)
+	resp, err := h.handleCallTool(context.Background(), params, 1)
+	if err != nil {
+		t.Fatalf("handleCallTool: %v", err)
+	}
+	result, ok := resp.(map[string]interface{})["result"].(*ToolResult)
+	if !ok {
+		t.Fatalf("response is not a tool result: %v", resp)
+	}
+	if !result.IsError {
+		t.Error("a failed mutation was not reported as a tool error")
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "Resource not accessible by integration") {
+		t.Errorf("result %q does not carry the GraphQL error", text)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+)
+
+type listRepositoriesArgs struct {
+	Type string `json:"type" description:"Repository type" jsonschema:"enum=all|owner|public|private|member,default=all"`
+	Sort string `json:"sort" description:"Sort order" jsonschema:"enum=created|updated|pushed|full_name,default=updated"`
+	Pagination
+}
+
+// repoRef is embedded in the arguments of tools that act on a repository.
+type repoRef struct {
+	Owner string `json:"owner" description:"Repository owner" jsonschema:"required"`
+	Repo  string `json:"repo" description:"Repository name" jsonschema:"required"`
+}
+
+type getRepositoryArgs struct {
+	repoRef
+}
+
+func (h *MCPHandler) repositoryTools() []Tool {
+	return []Tool{
+		NewTool("list_repositories", "List user repositories, or the repositories a GitHub App is installed on", true, h.listRepositories),
+		NewTool("get_repository", "Get repository information", true, h.getRepository),
+	}
+}
+
+func (h *MCPHandler) listRepositories(ctx context.Context, args listRepositoriesArgs) (*ToolResult, error) {
+	repos, err := h.userRepositories(ctx, args.Pagination, &github.RepositoryListOptions{
+		Type: args.Type,
+		Sort: args.Sort,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	return jsonResult(repos)
+}
+
+// userRepositories lists the repositories of the session's user. A GitHub
+// App has no user, so for App clients it lists the repositories of the
+// app's default installation instead, which take no type or sort.
+func (h *MCPHandler) userRepositories(ctx context.Context, p Pagination, listOpts *github.RepositoryListOptions) (*listPage[*github.Repository], error) {
+	if h.clientsFor(ctx).app {
+		return fetchPages(ctx, h, p, func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+			repos, resp, err := h.rest(ctx).Apps.ListRepos(ctx, &opts)
+			if err != nil {
+				return nil, resp, err
+			}
+			return repos.Repositories, resp, nil
+		})
+	}
+
+	return fetchPages(ctx, h, p, func(opts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		listOpts.ListOptions = opts
+		return h.rest(ctx).Repositories.List(ctx, "", listOpts)
+	})
+}
+
+func (h *MCPHandler) getRepository(ctx context.Context, args getRepositoryArgs) (*ToolResult, error) {
+	repository, _, err := h.rest(ctx).Repositories.Get(ctx, args.Owner, args.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	return jsonResult(repository)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// errInvalidResourceURI is returned when a URI matches a template but its
+// variables do not address a GitHub object.
+var errInvalidResourceURI = errors.New("invalid resource URI")
+
+// ResourceContents is one entry of a resources/read result. Exactly one of
+// Text and Blob is set; Blob holds base64-encoded binary data. Text is a
+// pointer so that an empty text resource still carries "text".
+type ResourceContents struct {
+	URI      string  `json:"uri"`
+	MIMEType string  `json:"mimeType,omitempty"`
+	Text     *string `json:"text,omitempty"`
+	Blob     string  `json:"blob,omitempty"`
+}
+
+func textResourceContents(uri, mimeType, text string) ResourceContents {
+	return ResourceContents{URI: uri, MIMEType: mimeType, Text: &text}
+}
+
+// ResourceTemplate is the resources/templates/list representation of a
+// resource template.
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// resourceTemplate serves every resource whose URI matches template.
+type resourceTemplate struct {
+	ResourceTemplate
+	toolset  string
+	template *uriTemplate
+	read     func(ctx context.Context, uri string, vars map[string]string) ([]ResourceContents, error)
+	// apiPath is the REST path polled for changes to a subscribed resource.
+	apiPath func(vars map[string]string) string
+}
+
+func (h *MCPHandler) repositoryResourceTemplates() []*resourceTemplate {
+	templates := []*resourceTemplate{
+		{
+			ResourceTemplate: ResourceTemplate{
+				URITemplate: "github://repos/{owner}/{repo}/contents/{path}{?ref}",
+				Name:        "repository-contents",
+				Description: "A file or directory in a repository, at the default branch or the given ref",
+			},
+			toolset: ToolsetRepos,
+			read:    h.readContentsResource,
+			apiPath: contentsAPIPath,
+		},
+		{
+			ResourceTemplate: ResourceTemplate{
+				URITemplate: "github://repos/{owner}/{repo}/contents{?ref}",
+				Name:        "repository-root",
+				Description: "The top-level directory of a repository, at the default branch or the given ref",
+				MIMEType:    "application/json",
+			},
+			toolset: ToolsetRepos,
+			read:    h.readContentsResource,
+			apiPath: contentsAPIPath,
+		},
+		{
+			ResourceTemplate: ResourceTemplate{
+				URITemplate: "github://repos/{owner}/{repo}/issues/{number}",
+				Name:        "issue",
+				Description: "An issue in a repository",
+				MIMEType:    "application/json",
+			},
+			toolset: ToolsetIssues,
+			read:    h.readIssueResource,
+			apiPath: func(vars map[string]string) string {
+				return fmt.Sprintf("repos/%s/%s/issues/%s", url.PathEscape(vars["owner"]), url.PathEscape(vars["repo"]), url.PathEscape(vars["number"]))
+			},
+		},
+		{
+			ResourceTemplate: ResourceTemplate{
+				URITemplate: "github://repos/{owner}/{repo}/pulls/{number}",
+				Name:        "pull-request",
+				Description: "A pull request in a repository",
+				MIMEType:    "application/json",
+			},
+			toolset: ToolsetPullRequests,
+			read:    h.readPullRequestResource,
+			apiPath: func(vars map[string]string) string {
+				return fmt.Sprintf("repos/%s/%s/pulls/%s", url.PathEscape(vars["owner"]), url.PathEscape(vars["repo"]), url.PathEscape(vars["number"]))
+			},
+		},
+	}
+
+	for _, t := range templates {
+		t.template = mustParseURITemplate(t.URITemplate)
+	}
+	return templates
+}
+
+// contentsAPIPath is the REST path of a file or directory. Without a path
+// variable it is the repository root.
+func contentsAPIPath(vars map[string]string) string {
+	p := fmt.Sprintf("repos/%s/%s/contents/%s", url.PathEscape(vars["owner"]), url.PathEscape(vars["repo"]), escapePath(vars["path"]))
+	if ref, ok := vars["ref"]; ok {
+		p += "?" + url.Values{"ref": {ref}}.Encode()
+	}
+	return p
+}
+
+func (h *MCPHandler) handleListResourceTemplates(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	templates := []ResourceTemplate{}
+	for _, t := range h.resourceTemplates {
+		if toolsetEnabled(ctx, t.toolset) {
+			templates = append(templates, t.ResourceTemplate)
+		}
+	}
+
+	return listResult(id, params, "resourceTemplates", templates), nil
+}
+
+// matchTemplate returns the first template available to the session in
+// ctx that matches uri, with the values of its variables.
+func (h *MCPHandler) matchTemplate(ctx context.Context, uri string) (*resourceTemplate, map[string]string, bool) {
+	for _, t := range h.resourceTemplates {
+		if !toolsetEnabled(ctx, t.toolset) {
+			continue
+		}
+		if vars, ok := t.template.Match(uri); ok {
+			return t, vars, true
+		}
+	}
+	return nil, nil, false
+}
+
+// readTemplateResource reads uri through the first template it matches.
+// ok is false when no template matches.
+func (h *MCPHandler) readTemplateResource(ctx context.Context, uri string) (contents []ResourceContents, ok bool, err error) {
+	t, vars, ok := h.matchTemplate(ctx, uri)
+	if !ok {
+		return nil, false, nil
+	}
+	contents, err = t.read(ctx, uri, vars)
+	return contents, true, err
+}
+
+func (h *MCPHandler) readContentsResource(ctx context.Context, uri string, vars map[string]string) ([]ResourceContents, error) {
+	file, dir, err := h.contents(ctx, vars["owner"], vars["repo"], vars["path"], vars["ref"])
+	if err != nil {
+		return nil, err
+	}
+
+	if file == nil {
+		data, err := json.Marshal(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal directory: %w", err)
+		}
+		return []ResourceContents{textResourceContents(uri, "application/json", string(data))}, nil
+	}
+
+	if file.Binary {
+		return []ResourceContents{{URI: uri, MIMEType: mimeTypeOf(file.Path, true), Blob: file.Content}}, nil
+	}
+	return []ResourceContents{textResourceContents(uri, mimeTypeOf(file.Path, false), file.Content)}, nil
+}
+
+func (h *MCPHandler) readIssueResource(ctx context.Context, uri string, vars map[string]string) ([]ResourceContents, error) {
+	number, err := resourceNumber(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	issue, _, err := h.rest(ctx).Issues.Get(ctx, vars["owner"], vars["repo"], number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	return jsonResourceContents(uri, issue)
+}
+
+func (h *MCPHandler) readPullRequestResource(ctx context.Context, uri string, vars map[string]string) ([]ResourceContents, error) {
+	number, err := resourceNumber(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	pull, _, err := h.rest(ctx).PullRequests.Get(ctx, vars["owner"], vars["repo"], number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	return jsonResourceContents(uri, pull)
+}
+
+func resourceNumber(vars map[string]string) (int, error) {
+	number, err := strconv.Atoi(vars["number"])
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("%w: %q is not an issue or pull request number", errInvalidResourceURI, vars["number"])
+	}
+	return number, nil
+}
+
+func jsonResourceContents(uri string, v interface{}) ([]ResourceContents, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+	return []ResourceContents{textResourceContents(uri, "application/json", string(data))}, nil
+}
+
+// mimeTypeOf guesses the MIME type of a file from its extension, falling
+// back to text/plain for text and application/octet-stream for binary data.
+func mimeTypeOf(name string, binary bool) string {
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		mimeType, _, _ = strings.Cut(mimeType, ";")
+		return mimeType
+	}
+	if binary {
+		return "application/octet-stream"
+	}
+	return "text/plain"
+}
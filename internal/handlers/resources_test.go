@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadContentsResource(t *testing.T) {
+	h := newTestHandler(t, Config{GitHubToken: "token"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/hello/contents/":
+			w.Write([]byte(`[{"type":"file","name":"empty.txt","path":"empty.txt"}]`))
+		case "/repos/octo/hello/contents/empty.txt":
+			w.Write([]byte(`{"type":"file","name":"empty.txt","path":"empty.txt","encoding":"base64","content":""}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"github://repos/octo/hello/contents/empty.txt", `"text":""`},
+		{"github://repos/octo/hello/contents", `"text":"[{`},
+		{"github://repos/octo/hello/contents?ref=main", `"text":"[{`},
+	}
+	for _, tt := range tests {
+		params, _ := json.Marshal(map[string]string{"uri": tt.uri})
+		resp, err := h.handleReadResource(context.Background(), params, 1)
+		if err != nil {
+			t.Errorf("%s: %v", tt.uri, err)
+			continue
+		}
+		data, _ := json.Marshal(resp)
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s: %s does not contain %s", tt.uri, data, tt.want)
+		}
+	}
+}
+
+func TestReadResourceRejectsInvalidNumbers(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uri := range []string{
+		"github://repos/octo/hello/issues/7/comments",
+		"github://repos/octo/hello/pulls/0",
+		"github://repos/octo/hello/issues/x",
+	} {
+		params, _ := json.Marshal(map[string]string{"uri": uri})
+		resp, err := h.handleReadResource(context.Background(), params, 1)
+		if err != nil {
+			t.Errorf("%s: %v", uri, err)
+			continue
+		}
+		data, _ := json.Marshal(resp)
+		if !strings.Contains(string(data), `"code":-32602`) {
+			t.Errorf("%s: %s, want an invalid params error", uri, data)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used to describe tool arguments.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+
+	pattern *regexp.Regexp
+	// nullable is set for properties backed by pointer or omitempty
+	// fields, for which null means the argument was omitted.
+	nullable bool
+}
+
+// schemaFor derives an object schema from an argument struct. Property
+// names come from the json tag, the text of the description tag becomes
+// the property description, and the jsonschema tag holds comma-separated
+// constraints:
+//
+//	required
+//	enum=a|b|c
+//	default=value
+//	minimum=n, maximum=n
+//	minLength=n, maxLength=n
+//	pattern=regexp
+//
+// pattern takes the rest of the tag, commas included, so it must come last.
+func schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		noExtras := false
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: &noExtras}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if isEmbedded(field) {
+				// Like encoding/json, flatten embedded structs into the parent.
+				embedded := schemaFor(field.Type)
+				for name, prop := range embedded.Properties {
+					schema.Properties[name] = prop
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+
+			prop := schemaFor(field.Type)
+			prop.Description = field.Tag.Get("description")
+			prop.nullable = field.Type.Kind() == reflect.Pointer || strings.Contains(field.Tag.Get("json"), ",omitempty")
+			if applyConstraints(prop, field.Type, field.Tag.Get("jsonschema")) {
+				schema.Required = append(schema.Required, name)
+			}
+			schema.Properties[name] = prop
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// applyConstraints parses a jsonschema tag into schema and reports whether
+// the property is required.
+func applyConstraints(schema *Schema, t reflect.Type, tag string) bool {
+	required := false
+	if tag == "" {
+		return required
+	}
+
+	if i := strings.Index(","+tag, ",pattern="); i >= 0 {
+		pattern := tag[i+len("pattern="):]
+		schema.Pattern = pattern
+		schema.pattern = regexp.MustCompile(pattern)
+		if tag = tag[:max(i-1, 0)]; tag == "" {
+			return required
+		}
+	}
+
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			required = true
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				schema.Enum = append(schema.Enum, parseTagValue(t, v))
+			}
+		case "default":
+			schema.Default = parseTagValue(t, value)
+		case "minimum":
+			schema.Minimum = parseFloat(value)
+		case "maximum":
+			schema.Maximum = parseFloat(value)
+		case "minLength":
+			schema.MinLength = parseInt(value)
+		case "maxLength":
+			schema.MaxLength = parseInt(value)
+		default:
+			panic(fmt.Sprintf("unknown jsonschema constraint %q", key))
+		}
+	}
+
+	return required
+}
+
+func isEmbedded(field reflect.StructField) bool {
+	return field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// parseTagValue converts a tag literal to the Go value matching the field's
+// kind, so defaults and enums serialise with the right JSON type.
+func parseTagValue(t reflect.Type, value string) interface{} {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+func parseFloat(value string) *float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("invalid jsonschema number %q", value))
+	}
+	return &f
+}
+
+func parseInt(value string) *int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid jsonschema integer %q", value))
+	}
+	return &i
+}
+
+// applyDefaults fills the fields of an argument struct from the defaults
+// declared in its schema. Values decoded afterwards take precedence.
+func applyDefaults(v reflect.Value, schema *Schema) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if isEmbedded(t.Field(i)) {
+			applyDefaults(v.Field(i), schema)
+			continue
+		}
+
+		name, ok := jsonFieldName(t.Field(i))
+		if !ok {
+			continue
+		}
+
+		prop := schema.Properties[name]
+		if prop == nil || prop.Default == nil {
+			continue
+		}
+
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		def := reflect.ValueOf(prop.Default)
+		if field.Kind() == reflect.Pointer {
+			ptr := reflect.New(field.Type().Elem())
+			if def.CanConvert(ptr.Elem().Type()) {
+				ptr.Elem().Set(def.Convert(ptr.Elem().Type()))
+				field.Set(ptr)
+			}
+			continue
+		}
+		if def.CanConvert(field.Type()) {
+			field.Set(def.Convert(field.Type()))
+		}
+	}
+}
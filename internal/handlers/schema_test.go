@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type schemaTestEmbedded struct {
+	Owner string `json:"owner" description:"Repository owner" jsonschema:"required"`
+}
+
+type schemaTestArgs struct {
+	schemaTestEmbedded
+	State    string   `json:"state" jsonschema:"enum=open|closed,default=open"`
+	PerPage  int      `json:"per_page" jsonschema:"minimum=1,maximum=100,default=30"`
+	Draft    *bool    `json:"draft" jsonschema:"default=true"`
+	Labels   []string `json:"labels"`
+	Ratio    float64  `json:"ratio"`
+	Branch   string   `json:"branch,omitempty" jsonschema:"minLength=1,maxLength=255,pattern=^[a-z]+$"`
+	Ignored  string   `json:"-"`
+	internal string
+}
+
+func TestSchemaFor(t *testing.T) {
+	got, err := json.Marshal(schemaFor(reflect.TypeOf(schemaTestArgs{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{
+		"type": "object",
+		"properties": {
+			"owner": {"type": "string", "description": "Repository owner"},
+			"state": {"type": "string", "enum": ["open", "closed"], "default": "open"},
+			"per_page": {"type": "integer", "default": 30, "minimum": 1, "maximum": 100},
+			"draft": {"type": "boolean", "default": true},
+			"labels": {"type": "array", "items": {"type": "string"}},
+			"ratio": {"type": "number"},
+			"branch": {"type": "string", "minLength": 1, "maxLength": 255, "pattern": "^[a-z]+$"}
+		},
+		"required": ["owner"],
+		"additionalProperties": false
+	}`
+
+	var gotValue, wantValue interface{}
+	json.Unmarshal(got, &gotValue)
+	json.Unmarshal([]byte(want), &wantValue)
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("schema:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestToolArgumentDefaults(t *testing.T) {
+	var got schemaTestArgs
+	tool := NewTool("t", "", true, func(ctx context.Context, args schemaTestArgs) (*ToolResult, error) {
+		got = args
+		return textResult("ok"), nil
+	})
+
+	tests := []struct {
+		args    string
+		state   string
+		perPage int
+		draft   bool
+	}{
+		{`{"owner":"octo"}`, "open", 30, true},
+		{`{"owner":"octo","state":"closed","per_page":5,"draft":false}`, "closed", 5, false},
+	}
+	for _, tt := range tests {
+		got = schemaTestArgs{}
+		if _, err := tool.Call(context.Background(), json.RawMessage(tt.args)); err != nil {
+			t.Errorf("Call(%s): %v", tt.args, err)
+			continue
+		}
+		if got.Owner != "octo" || got.State != tt.state || got.PerPage != tt.perPage || got.Draft == nil || *got.Draft != tt.draft {
+			t.Errorf("Call(%s) decoded %+v", tt.args, got)
+		}
+	}
+}
+
+func TestApplyConstraintsPattern(t *testing.T) {
+	tests := []struct {
+		tag      string
+		required bool
+		pattern  string
+	}{
+		{"pattern=^[0-9a-f]{40}$", false, "^[0-9a-f]{40}$"},
+		{"required,pattern=^a{1,3}$", true, "^a{1,3}$"},
+		{"minLength=1,pattern=^(a|b),c$", false, "^(a|b),c$"},
+		{"required", true, ""},
+	}
+	for _, tt := range tests {
+		schema := &Schema{Type: "string"}
+		required := applyConstraints(schema, reflect.TypeOf(""), tt.tag)
+		if required != tt.required || schema.Pattern != tt.pattern {
+			t.Errorf("%q: required %v, pattern %q; want %v, %q", tt.tag, required, schema.Pattern, tt.required, tt.pattern)
+		}
+		if tt.pattern != "" && schema.pattern.String() != tt.pattern {
+			t.Errorf("%q: compiled pattern %q", tt.tag, schema.pattern)
+		}
+	}
+}
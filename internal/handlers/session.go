@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+)
+
+// SupportedProtocolVersions lists the MCP revisions this server can speak,
+// newest first.
+var SupportedProtocolVersions = []string{
+	LatestProtocolVersion,
+	"2024-11-05",
+}
+
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type ClientCapabilities struct {
+	Roots        *RootsCapability       `json:"roots,omitempty"`
+	Sampling     map[string]interface{} `json:"sampling,omitempty"`
+	Elicitation  map[string]interface{} `json:"elicitation,omitempty"`
+	Experimental map[string]interface{} `json:"experimental,omitempty"`
+}
+
+type RootsCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+// SessionState is the per-connection MCP state shared between a transport
+// and the handler. Transports create one per session and attach it to the
+// request context with WithSession.
+type SessionState struct {
+	mu sync.RWMutex
+	// initialized is set when initialize is accepted, and ready once the
+	// client confirms with notifications/initialized.
+	initialized        bool
+	ready              bool
+	protocolVersion    string
+	clientInfo         ClientInfo
+	clientCapabilities ClientCapabilities
+	notifier           func(message interface{})
+	disabledToolsets   map[string]bool
+	principal          string
+	github             *githubClients
+}
+
+func NewSessionState() *SessionState {
+	return &SessionState{}
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context carrying the given session state.
+func WithSession(ctx context.Context, state *SessionState) context.Context {
+	return context.WithValue(ctx, sessionKey{}, state)
+}
+
+// SessionFromContext returns the session state attached by WithSession.
+func SessionFromContext(ctx context.Context) (*SessionState, bool) {
+	state, ok := ctx.Value(sessionKey{}).(*SessionState)
+	return state, ok && state != nil
+}
+
+// Initialized reports whether the session has accepted an initialize
+// request.
+func (s *SessionState) Initialized() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initialized
+}
+
+// Ready reports whether initialization has completed, so the session may
+// make requests other than ping.
+func (s *SessionState) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ready
+}
+
+func (s *SessionState) ProtocolVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion
+}
+
+func (s *SessionState) ClientInfo() ClientInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+func (s *SessionState) ClientCapabilities() ClientCapabilities {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientCapabilities
+}
+
+// ToolsetEnabled reports whether the session may use the given toolset.
+// Tools outside any toolset are always enabled.
+func (s *SessionState) ToolsetEnabled(toolset string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return toolset == "" || !s.disabledToolsets[toolset]
+}
+
+// setToolsetEnabled switches a toolset on or off and reports whether that
+// changed anything.
+func (s *SessionState) setToolsetEnabled(toolset string, enabled bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.disabledToolsets[toolset] == enabled {
+		return false
+	}
+	if s.disabledToolsets == nil {
+		s.disabledToolsets = make(map[string]bool)
+	}
+	if enabled {
+		delete(s.disabledToolsets, toolset)
+	} else {
+		s.disabledToolsets[toolset] = true
+	}
+	return true
+}
+
+// Principal identifies the authenticated client that owns the session, or
+// is empty for anonymous sessions.
+func (s *SessionState) Principal() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.principal
+}
+
+func (s *SessionState) SetPrincipal(principal string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.principal = principal
+}
+
+func (s *SessionState) githubClients() *githubClients {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.github
+}
+
+func (s *SessionState) setGitHubClients(clients *githubClients) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.github = clients
+}
+
+// SetNotifier installs the function that delivers server-initiated
+// notifications to the session's client. Without one, notifications for the
+// session are dropped.
+func (s *SessionState) SetNotifier(notifier func(message interface{})) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = notifier
+}
+
+// hasNotifier reports whether the session can be sent notifications.
+func (s *SessionState) hasNotifier() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.notifier != nil
+}
+
+// notify sends a notification to the session's client, if it can receive
+// one.
+func (s *SessionState) notify(method string, params interface{}) {
+	s.mu.RLock()
+	notifier := s.notifier
+	s.mu.RUnlock()
+
+	if notifier != nil {
+		notifier(rpcNotification(method, params))
+	}
+}
+
+// initialize records the outcome of the handshake. It reports false if the
+// session had already been initialized.
+func (s *SessionState) initialize(version string, info ClientInfo, caps ClientCapabilities) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.initialized {
+		return false
+	}
+
+	s.initialized = true
+	s.protocolVersion = version
+	s.clientInfo = info
+	s.clientCapabilities = caps
+	return true
+}
+
+// markReady completes initialization. It does nothing before initialize.
+func (s *SessionState) markReady() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ready = s.initialized
+}
+
+// negotiateProtocolVersion echoes the client's version when we support it
+// and otherwise offers our latest, leaving the client to disconnect.
+func negotiateProtocolVersion(requested string) string {
+	for _, version := range SupportedProtocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return LatestProtocolVersion
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often subscribed resources are checked for
+// changes when the handler is not configured otherwise.
+const DefaultPollInterval = time.Minute
+
+// subscriptions tracks which sessions subscribe to which resources and
+// polls GitHub for changes while any subscription exists. Polls use the
+// ETags of the last responses, so unchanged resources cost a 304 that does
+// not count against the rate limit. Paginated resources are polled page by
+// page, up to the handler's page cap.
+type subscriptions struct {
+	h        *MCPHandler
+	interval time.Duration
+
+	mu      sync.Mutex
+	watches map[watchKey]*watch
+	running bool
+}
+
+// watchKey identifies a watch. Sessions acting as different GitHub
+// identities may see a resource differently, so each session's
+// subscription is polled on its own, with whatever clients the session
+// acts with at the time.
+type watchKey struct {
+	session *SessionState
+	uri     string
+}
+
+// watch is one subscribed resource, with the ETag of each of its pages.
+type watch struct {
+	apiPath string
+	etags   []string
+}
+
+func newSubscriptions(h *MCPHandler, interval time.Duration) *subscriptions {
+	return &subscriptions{
+		h:        h,
+		interval: interval,
+		watches:  make(map[watchKey]*watch),
+	}
+}
+
+func (h *MCPHandler) handleSubscribe(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	session, uri, errResp := h.subscriptionParams(ctx, params, id)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	apiPath, ok := h.resourceAPIPath(ctx, uri)
+	if !ok {
+		return rpcError(id, codeInvalidParams, fmt.Sprintf("Unknown resource URI: %s", uri)), nil
+	}
+
+	if err := h.subscriptions.subscribe(ctx, watchKey{session: session, uri: uri}, apiPath); err != nil {
+		return nil, err
+	}
+
+	return rpcResult(id, map[string]interface{}{}), nil
+}
+
+func (h *MCPHandler) handleUnsubscribe(ctx context.Context, params json.RawMessage, id interface{}) (interface{}, error) {
+	session, uri, errResp := h.subscriptionParams(ctx, params, id)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	h.subscriptions.unsubscribe(watchKey{session: session, uri: uri})
+	return rpcResult(id, map[string]interface{}{}), nil
+}
+
+func (h *MCPHandler) subscriptionParams(ctx context.Context, params json.RawMessage, id interface{}) (*SessionState, string, map[string]interface{}) {
+	var req struct {
+		URI string `json:"uri"`
+	}
+
+	if err := json.Unmarshal(params, &req); err != nil || req.URI == "" {
+		return nil, "", rpcError(id, codeInvalidParams, "Invalid subscription params")
+	}
+
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, "", rpcError(id, codeInvalidRequest, "Subscriptions require a session")
+	}
+
+	return session, req.URI, nil
+}
+
+// CloseSession drops everything the handler holds for a session that has
+// ended. Transports call it when they discard the session.
+func (h *MCPHandler) CloseSession(session *SessionState) {
+	h.subscriptions.unsubscribeAll(session)
+
+	h.sessionsMu.Lock()
+	delete(h.sessions, session)
+	h.sessionsMu.Unlock()
+}
+
+// resourceAPIPath maps a resource URI to the REST path polled for changes.
+func (h *MCPHandler) resourceAPIPath(ctx context.Context, uri string) (string, bool) {
+	switch app := h.clientsFor(ctx).app; {
+	case uri == "github://repositories" && app:
+		return "installation/repositories?per_page=100", true
+	case uri == "github://repositories":
+		return "user/repos?type=all&per_page=100", true
+	case uri == "github://user" && !app:
+		return "user", true
+	}
+
+	if t, vars, ok := h.matchTemplate(ctx, uri); ok {
+		return t.apiPath(vars), true
+	}
+	return "", false
+}
+
+// subscribe subscribes a session to a resource. It fetches the resource
+// to record the ETags changes are measured from, which also rejects
+// resources that do not exist. Subscribing again changes nothing.
+func (s *subscriptions) subscribe(ctx context.Context, key watchKey, apiPath string) error {
+	s.mu.Lock()
+	_, ok := s.watches[key]
+	s.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	etags, err := fetchETags(ctx, s.h.sessionClients(key.session), apiPath, nil, s.h.maxPages)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", key.uri, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.watches[key]; !ok {
+		s.watches[key] = &watch{apiPath: apiPath, etags: etags}
+	}
+
+	if !s.running {
+		s.running = true
+		go s.poll()
+	}
+	return nil
+}
+
+func (s *subscriptions) unsubscribe(key watchKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watches, key)
+}
+
+func (s *subscriptions) unsubscribeAll(session *SessionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.watches {
+		if key.session == session {
+			delete(s.watches, key)
+		}
+	}
+}
+
+// poll checks every watched resource once per interval. It exits once
+// the last subscription is gone; the next subscribe starts it again.
+func (s *subscriptions) poll() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		if len(s.watches) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		pending := make(map[watchKey]watch, len(s.watches))
+		for key, w := range s.watches {
+			pending[key] = watch{apiPath: w.apiPath, etags: w.etags}
+		}
+		s.mu.Unlock()
+
+		for key, w := range pending {
+			s.check(key, w.apiPath, w.etags)
+		}
+	}
+}
+
+// check polls one resource and notifies its subscriber if it changed.
+// A resource that disappears counts as changed, once.
+func (s *subscriptions) check(key watchKey, apiPath string, etags []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+
+	current, err := fetchETags(ctx, s.h.sessionClients(key.session), apiPath, etags, s.h.maxPages)
+	if err != nil {
+		apiErr, ok := asAPIError(err)
+		if !ok || apiErr.Status != http.StatusNotFound {
+			// Transient failures are retried on the next tick.
+			return
+		}
+		current = nil
+	}
+	if slices.Equal(current, etags) {
+		return
+	}
+
+	s.mu.Lock()
+	w, ok := s.watches[key]
+	if !ok || !slices.Equal(w.etags, etags) {
+		s.mu.Unlock()
+		return
+	}
+	w.etags = current
+	s.mu.Unlock()
+
+	key.session.notify("notifications/resources/updated", map[string]interface{}{"uri": key.uri})
+}
+
+// fetchETags GETs every page of apiPath, up to maxPages, and returns their
+// ETags. Requests for pages in etags are conditional, and unchanged pages
+// return their ETag from etags.
+func fetchETags(ctx context.Context, clients *githubClients, apiPath string, etags []string, maxPages int) ([]string, error) {
+	var current []string
+	for page := 1; page > 0 && page <= maxPages; {
+		var previous string
+		if page <= len(etags) {
+			previous = etags[page-1]
+		}
+
+		etag, next, err := fetchETag(ctx, clients, pagePath(apiPath, page), previous)
+		if err != nil {
+			return nil, err
+		}
+		current = append(current, etag)
+
+		// A 304 need not carry the Link header, but an unchanged page
+		// is followed by the pages that followed it before.
+		if next == 0 && etag == previous && page < len(etags) {
+			next = page + 1
+		}
+		page = next
+	}
+	return current, nil
+}
+
+// fetchETag GETs apiPath and returns its ETag and the next page of the
+// results, if any. When etag is set the request is conditional, and an
+// unchanged resource returns etag itself.
+func fetchETag(ctx context.Context, clients *githubClients, apiPath, etag string) (string, int, error) {
+	req, err := clients.rest.NewRequest(http.MethodGet, apiPath, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := clients.rest.Do(ctx, req, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return etag, resp.NextPage, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.Header.Get("ETag") == "" {
+		return "", 0, errors.New("response carried no ETag")
+	}
+	return resp.Header.Get("ETag"), resp.NextPage, nil
+}
+
+// pagePath returns apiPath for the given page of its results.
+func pagePath(apiPath string, page int) string {
+	if page == 1 {
+		return apiPath
+	}
+	separator := "?"
+	if strings.Contains(apiPath, "?") {
+		separator = "&"
+	}
+	return apiPath + separator + "page=" + strconv.Itoa(page)
+}
+
+// escapePath percent-encodes each segment of a repository path.
+func escapePath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestHandler returns a handler whose GitHub clients all talk to github
+// instead of api.github.com.
+func newTestHandler(t *testing.T, config Config, github http.Handler) *MCPHandler {
+	t.Helper()
+	server := httptest.NewServer(github)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+
+	h, err := NewMCPHandler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.transport = redirectTransport{target: target, base: h.transport}
+	if config.GitHubToken != "" {
+		h.fallback = h.newGitHubClients(staticToken(config.GitHubToken))
+	}
+	return h
+}
+
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return t.base.RoundTrip(req)
+}
+
+func TestSubscriptionFollowsSessionCredentials(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	h := newTestHandler(t, Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		seen = append(seen, token)
+		mu.Unlock()
+		w.Header().Set("ETag", `"`+token+`"`)
+		w.Write([]byte("{}"))
+	}))
+
+	session := NewSessionState()
+	var notified []string
+	session.SetNotifier(func(message interface{}) {
+		data, _ := json.Marshal(message)
+		notified = append(notified, string(data))
+	})
+	ctx := WithSession(context.Background(), session)
+	h.ProcessRPC(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`))
+	h.ProcessRPC(ctx, json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	h.SetGitHubToken(session, "alice")
+
+	const subscribe = `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"github://user"}}`
+	if resp, _ := json.Marshal(h.ProcessRPC(ctx, json.RawMessage(subscribe))); strings.Contains(string(resp), `"error"`) {
+		t.Fatalf("subscribe: %s", resp)
+	}
+
+	// The session's identity changes; polls use the new one.
+	h.SetGitHubToken(session, "bob")
+	key := watchKey{session: session, uri: "github://user"}
+	h.subscriptions.check(key, "user", []string{`"alice"`})
+	if len(notified) != 1 {
+		t.Errorf("%d notifications after the resource changed, want 1", len(notified))
+	}
+	mu.Lock()
+	if last := seen[len(seen)-1]; last != "bob" {
+		t.Errorf("poll authenticated as %q, want bob", last)
+	}
+	mu.Unlock()
+
+	const unsubscribe = `{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":"github://user"}}`
+	h.ProcessRPC(ctx, json.RawMessage(unsubscribe))
+	if n := len(h.subscriptions.watches); n != 0 {
+		t.Errorf("%d watches left after unsubscribing with new credentials", n)
+	}
+}
+
+func TestSubscriptionPollsEveryPage(t *testing.T) {
+	var mu sync.Mutex
+	etags := map[string]string{"1": `"a"`, "2": `"b"`}
+	h := newTestHandler(t, Config{GitHubToken: "token"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		mu.Lock()
+		etag := etags[page]
+		mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if page == "1" {
+			w.Header().Set("Link", `<https://api.github.com/user/repos?type=all&per_page=100&page=2>; rel="next"`)
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("[]"))
+	}))
+
+	session := NewSessionState()
+	var notified int
+	session.SetNotifier(func(interface{}) { notified++ })
+	ctx := WithSession(context.Background(), session)
+
+	const uri = "github://repositories"
+	apiPath, _ := h.resourceAPIPath(ctx, uri)
+	key := watchKey{session: session, uri: uri}
+	if err := h.subscriptions.subscribe(ctx, key, apiPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.subscriptions.watches[key].etags; !slices.Equal(got, []string{`"a"`, `"b"`}) {
+		t.Fatalf("subscribed with ETags %q, want both pages", got)
+	}
+
+	h.subscriptions.check(key, apiPath, []string{`"a"`, `"b"`})
+	if notified != 0 {
+		t.Fatalf("%d notifications for an unchanged resource", notified)
+	}
+
+	mu.Lock()
+	etags["2"] = `"c"`
+	mu.Unlock()
+	h.subscriptions.check(key, apiPath, []string{`"a"`, `"b"`})
+	if notified != 1 {
+		t.Errorf("%d notifications after the second page changed, want 1", notified)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Tool is a single callable MCP tool.
+type Tool interface {
+	Name() string
+	Description() string
+	InputSchema() *Schema
+	// ReadOnly reports whether the tool only reads from GitHub. Tools that
+	// write are hidden and refused when the handler is in read-only mode.
+	ReadOnly() bool
+	Call(ctx context.Context, args json.RawMessage) (*ToolResult, error)
+}
+
+type ToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type ToolAnnotations struct {
+	ReadOnlyHint bool `json:"readOnlyHint"`
+}
+
+// ToolInfo is the tools/list representation of a tool.
+type ToolInfo struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema *Schema          `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ArgumentError reports tool arguments that failed schema validation or
+// could not be decoded into the tool's argument type.
+type ArgumentError struct {
+	Tool       string
+	Violations []Violation
+	Err        error
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid arguments for %s: %v", e.Tool, e.Err)
+	}
+
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Path + ": " + v.Message
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+type typedTool[A any] struct {
+	name        string
+	description string
+	readOnly    bool
+	schema      *Schema
+	handler     func(ctx context.Context, args A) (*ToolResult, error)
+}
+
+// NewTool builds a Tool whose input schema is generated from the argument
+// struct A. Arguments are decoded into A, after schema defaults have been
+// applied, before the handler runs.
+func NewTool[A any](name, description string, readOnly bool, handler func(ctx context.Context, args A) (*ToolResult, error)) Tool {
+	var zero A
+	return &typedTool[A]{
+		name:        name,
+		description: description,
+		readOnly:    readOnly,
+		schema:      schemaFor(reflect.TypeOf(zero)),
+		handler:     handler,
+	}
+}
+
+func (t *typedTool[A]) Name() string         { return t.name }
+func (t *typedTool[A]) Description() string  { return t.description }
+func (t *typedTool[A]) InputSchema() *Schema { return t.schema }
+func (t *typedTool[A]) ReadOnly() bool       { return t.readOnly }
+
+func (t *typedTool[A]) Call(ctx context.Context, raw json.RawMessage) (*ToolResult, error) {
+	// Omitted arguments are treated as an empty object.
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, &ArgumentError{Tool: t.name, Err: err}
+	}
+
+	// Clients often send null for an optional argument they leave out.
+	if t.schema.omitNulls(value) {
+		var err error
+		if raw, err = json.Marshal(value); err != nil {
+			return nil, &ArgumentError{Tool: t.name, Err: err}
+		}
+	}
+
+	if violations := t.schema.Validate(value); len(violations) > 0 {
+		return nil, &ArgumentError{Tool: t.name, Violations: violations}
+	}
+
+	var args A
+	applyDefaults(reflect.ValueOf(&args), t.schema)
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, &ArgumentError{Tool: t.name, Err: err}
+	}
+
+	return t.handler(ctx, args)
+}
+
+// ToolRegistry holds the tools served by a handler, in registration order,
+// along with the toolset each belongs to.
+type ToolRegistry struct {
+	mu       sync.RWMutex
+	tools    map[string]Tool
+	toolsets map[string]string
+	order    []string
+}
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]Tool), toolsets: make(map[string]string)}
+}
+
+// Register adds tools to a toolset; the empty toolset holds tools that are
+// always available. Registering two tools under one name is a programming
+// error and panics.
+func (r *ToolRegistry) Register(toolset string, tools ...Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tool := range tools {
+		if _, exists := r.tools[tool.Name()]; exists {
+			panic(fmt.Sprintf("tool %q registered twice", tool.Name()))
+		}
+		r.tools[tool.Name()] = tool
+		r.toolsets[tool.Name()] = toolset
+		r.order = append(r.order, tool.Name())
+	}
+}
+
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Toolset returns the toolset a tool was registered under.
+func (r *ToolRegistry) Toolset(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.toolsets[name]
+}
+
+// List returns the registered tools, leaving out write tools when readOnly
+// is set.
+func (r *ToolRegistry) List(readOnly bool) []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tool := r.tools[name]
+		if readOnly && !tool.ReadOnly() {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+func toolInfo(tool Tool) ToolInfo {
+	return ToolInfo{
+		Name:        tool.Name(),
+		Description: tool.Description(),
+		InputSchema: tool.InputSchema(),
+		Annotations: &ToolAnnotations{ReadOnlyHint: tool.ReadOnly()},
+	}
+}
+
+func textResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// errorResult returns text as a failed tool result, which the model sees and
+// can act on.
+func errorResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}, IsError: true}
+}
+
+// jsonResult returns v marshalled as the text content of a tool result.
+func jsonResult(v interface{}) (*ToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return textResult(string(data)), nil
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Toolsets group related tools, resource templates and prompts so that a
+// session can switch them off together. Everything is enabled when a
+// session starts.
+const (
+	ToolsetRepos        = "repos"
+	ToolsetIssues       = "issues"
+	ToolsetPullRequests = "pull_requests"
+)
+
+// promptsToolset is the toolset the analyze_repository prompt belongs to.
+const promptsToolset = ToolsetRepos
+
+var toolsetDescriptions = map[string]string{
+	ToolsetRepos:        "Repositories and their file contents",
+	ToolsetIssues:       "Issues and issue comments",
+	ToolsetPullRequests: "Pull requests, reviews and merges",
+}
+
+type toolsetArgs struct {
+	Toolset string `json:"toolset" description:"Name of the toolset" jsonschema:"required,enum=repos|issues|pull_requests"`
+}
+
+type toolsetInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// toolsetTools manage the toolsets of the calling session. They belong to
+// no toolset, so they cannot be switched off.
+func (h *MCPHandler) toolsetTools() []Tool {
+	return []Tool{
+		NewTool("list_toolsets", "List the toolsets and whether each is enabled for this session", true, h.listToolsets),
+		NewTool("enable_toolset", "Enable a toolset for this session, making its tools, resources and prompts available", true, h.enableToolset),
+		NewTool("disable_toolset", "Disable a toolset for this session, hiding its tools, resources and prompts", true, h.disableToolset),
+	}
+}
+
+func (h *MCPHandler) listToolsets(ctx context.Context, args struct{}) (*ToolResult, error) {
+	names := make([]string, 0, len(toolsetDescriptions))
+	for name := range toolsetDescriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	toolsets := make([]toolsetInfo, len(names))
+	for i, name := range names {
+		toolsets[i] = toolsetInfo{
+			Name:        name,
+			Description: toolsetDescriptions[name],
+			Enabled:     toolsetEnabled(ctx, name),
+		}
+	}
+
+	return jsonResult(toolsets)
+}
+
+func (h *MCPHandler) enableToolset(ctx context.Context, args toolsetArgs) (*ToolResult, error) {
+	return h.setToolset(ctx, args.Toolset, true)
+}
+
+func (h *MCPHandler) disableToolset(ctx context.Context, args toolsetArgs) (*ToolResult, error) {
+	return h.setToolset(ctx, args.Toolset, false)
+}
+
+func (h *MCPHandler) setToolset(ctx context.Context, toolset string, enabled bool) (*ToolResult, error) {
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("toolsets can only be changed within a session")
+	}
+
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+
+	if !session.setToolsetEnabled(toolset, enabled) {
+		return textResult(fmt.Sprintf("Toolset %s is already %s", toolset, state)), nil
+	}
+	h.notifyToolsetChanged(session, toolset)
+
+	return textResult(fmt.Sprintf("Toolset %s %s", toolset, state)), nil
+}
+
+// toolsetEnabled reports whether the session in ctx may use toolset.
+// Requests outside a session see every toolset.
+func toolsetEnabled(ctx context.Context, toolset string) bool {
+	session, ok := SessionFromContext(ctx)
+	return !ok || session.ToolsetEnabled(toolset)
+}
+
+// notifyToolsetChanged tells a session which of its lists changed when
+// toolset was switched on or off.
+func (h *MCPHandler) notifyToolsetChanged(session *SessionState, toolset string) {
+	for _, tool := range h.tools.List(h.ReadOnly()) {
+		if h.tools.Toolset(tool.Name()) == toolset {
+			session.notify("notifications/tools/list_changed", nil)
+			break
+		}
+	}
+
+	for _, t := range h.resourceTemplates {
+		if t.toolset == toolset {
+			session.notify("notifications/resources/list_changed", nil)
+			break
+		}
+	}
+
+	if toolset == promptsToolset {
+		session.notify("notifications/prompts/list_changed", nil)
+	}
+}
+
+// ReadOnly reports whether write tools are currently withheld.
+func (h *MCPHandler) ReadOnly() bool {
+	return h.readOnly.Load()
+}
+
+// SetReadOnly switches read-only mode at runtime. Sessions are told their
+// tool list changed when the mode actually flips.
+func (h *MCPHandler) SetReadOnly(readOnly bool) {
+	if h.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+
+	h.sessionsMu.Lock()
+	sessions := make([]*SessionState, 0, len(h.sessions))
+	for session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.sessionsMu.Unlock()
+
+	for _, session := range sessions {
+		session.notify("notifications/tools/list_changed", nil)
+	}
+}
+
+// trackSession records an initialized session so runtime changes can be
+// announced to it. CloseSession forgets it again.
+func (h *MCPHandler) trackSession(session *SessionState) {
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+	h.sessions[session] = struct{}{}
+}
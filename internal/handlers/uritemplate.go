@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// uriTemplate matches URIs against an RFC 6570 template. Only the
+// expressions needed to address GitHub objects are supported:
+//
+//	{var}    a single path segment, percent-decoded
+//	{+var}   reserved expansion, which may span several segments
+//	{?a,b}   optional query parameters
+//
+// A simple {var} that ends the path may also span several segments, so a
+// file path is accepted whether the client expanded its slashes as / or
+// as %2F.
+type uriTemplate struct {
+	raw   string
+	re    *regexp.Regexp
+	vars  []string
+	query []string
+}
+
+var templateExpr = regexp.MustCompile(`\{([+?]?)([A-Za-z0-9_,]+)\}`)
+
+func parseURITemplate(raw string) (*uriTemplate, error) {
+	t := &uriTemplate{raw: raw}
+
+	matches := templateExpr.FindAllStringSubmatchIndex(raw, -1)
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	last := 0
+	for i, m := range matches {
+		literal := raw[last:m[0]]
+		if strings.ContainsAny(literal, "{}") {
+			return nil, fmt.Errorf("uri template %q: malformed expression", raw)
+		}
+		pattern.WriteString(regexp.QuoteMeta(literal))
+		last = m[1]
+
+		op, names := raw[m[2]:m[3]], strings.Split(raw[m[4]:m[5]], ",")
+		switch op {
+		case "?":
+			if m[1] != len(raw) {
+				return nil, fmt.Errorf("uri template %q: query expression must come last", raw)
+			}
+			t.query = append(t.query, names...)
+			pattern.WriteString(`(?:\?([^#]*))?`)
+			continue
+		case "+":
+		default:
+			// A trailing simple expression may span segments; see above.
+			endsPath := m[1] == len(raw) ||
+				i+1 < len(matches) && matches[i+1][0] == m[1] && raw[matches[i+1][2]:matches[i+1][3]] == "?"
+			if endsPath {
+				op = "+"
+			}
+		}
+		if len(names) != 1 {
+			return nil, fmt.Errorf("uri template %q: only query expressions may list several variables", raw)
+		}
+
+		t.vars = append(t.vars, names[0])
+		if op == "+" {
+			pattern.WriteString(`([^?#]+)`)
+		} else {
+			pattern.WriteString(`([^/?#]+)`)
+		}
+	}
+
+	literal := raw[last:]
+	if strings.ContainsAny(literal, "{}") {
+		return nil, fmt.Errorf("uri template %q: malformed expression", raw)
+	}
+	pattern.WriteString(regexp.QuoteMeta(literal))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("uri template %q: %w", raw, err)
+	}
+	t.re = re
+	return t, nil
+}
+
+func mustParseURITemplate(raw string) *uriTemplate {
+	t, err := parseURITemplate(raw)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *uriTemplate) String() string {
+	return t.raw
+}
+
+// Match reports whether uri matches the template and returns the values of
+// its variables. Query variables missing from the URI are absent from the
+// map.
+func (t *uriTemplate) Match(uri string) (map[string]string, bool) {
+	m := t.re.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+
+	vars := make(map[string]string, len(t.vars)+len(t.query))
+	for i, name := range t.vars {
+		value, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = value
+	}
+
+	if len(t.query) > 0 && m[len(t.vars)+1] != "" {
+		query, err := url.ParseQuery(m[len(t.vars)+1])
+		if err != nil {
+			return nil, false
+		}
+		for _, name := range t.query {
+			if query.Has(name) {
+				vars[name] = query.Get(name)
+			}
+		}
+	}
+
+	return vars, true
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestURITemplateMatch(t *testing.T) {
+	tests := []struct {
+		template string
+		uri      string
+		want     map[string]string
+	}{
+		{"github://repos/{owner}/{repo}/issues/{number}", "github://repos/octo/hello/issues/7",
+			map[string]string{"owner": "octo", "repo": "hello", "number": "7"}},
+		// The last variable may span segments; readers validate it.
+		{"github://repos/{owner}/{repo}/issues/{number}", "github://repos/octo/hello/issues/7/comments",
+			map[string]string{"owner": "octo", "repo": "hello", "number": "7/comments"}},
+		{"github://repos/{owner}/{repo}/issues/{number}", "github://repos/octo/issues/7", nil},
+		{"github://repos/{owner}/{repo}/issues/{number}", "github://repos/o%20k/hello/issues/7",
+			map[string]string{"owner": "o k", "repo": "hello", "number": "7"}},
+		{"github://repos/{owner}/{repo}/issues/{number}", "github://repos/%zz/hello/issues/7", nil},
+
+		// A trailing simple expression spans segments, whether their slashes
+		// are expanded or escaped.
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents/docs/README.md",
+			map[string]string{"owner": "octo", "repo": "hello", "path": "docs/README.md"}},
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents/docs%2FREADME.md",
+			map[string]string{"owner": "octo", "repo": "hello", "path": "docs/README.md"}},
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents/a.go?ref=v1.0",
+			map[string]string{"owner": "octo", "repo": "hello", "path": "a.go", "ref": "v1.0"}},
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents/a.go?other=1",
+			map[string]string{"owner": "octo", "repo": "hello", "path": "a.go"}},
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents/", nil},
+		{"github://repos/{owner}/{repo}/contents/{path}{?ref}", "github://repos/octo/hello/contents", nil},
+
+		{"github://repos/{owner}/{repo}/contents{?ref}", "github://repos/octo/hello/contents",
+			map[string]string{"owner": "octo", "repo": "hello"}},
+		{"github://repos/{owner}/{repo}/contents{?ref}", "github://repos/octo/hello/contents?ref=main",
+			map[string]string{"owner": "octo", "repo": "hello", "ref": "main"}},
+		{"github://repos/{owner}/{repo}/contents{?ref}", "github://repos/octo/hello/contents/a.go", nil},
+
+		{"file:///{+path}", "file:///a/b/c", map[string]string{"path": "a/b/c"}},
+		{"x://{a}/y", "x://1/2/y", nil},
+		{"x://{a}/y", "X://1/y", nil},
+	}
+	for _, tt := range tests {
+		vars, ok := mustParseURITemplate(tt.template).Match(tt.uri)
+		if ok != (tt.want != nil) || ok && !reflect.DeepEqual(vars, tt.want) {
+			t.Errorf("%s.Match(%s) = %v, %v; want %v", tt.template, tt.uri, vars, ok, tt.want)
+		}
+	}
+}
+
+func TestParseURITemplateRejects(t *testing.T) {
+	for _, raw := range []string{
+		"x://{a",
+		"x://a}",
+		"x://{?q}/{a}",
+		"x://{a,b}/c",
+	} {
+		if _, err := parseURITemplate(raw); err == nil {
+			t.Errorf("parseURITemplate(%q) succeeded", raw)
+		}
+	}
+}
+
+func TestMatchTemplateFollowsToolsets(t *testing.T) {
+	h, err := NewMCPHandler(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := NewSessionState()
+	ctx := WithSession(context.Background(), session)
+
+	if tmpl, _, ok := h.matchTemplate(ctx, "github://repos/octo/hello/issues/1"); !ok || tmpl.Name != "issue" {
+		t.Fatalf("issue URI matched %v, %v", tmpl, ok)
+	}
+	session.setToolsetEnabled(ToolsetIssues, false)
+	if _, _, ok := h.matchTemplate(ctx, "github://repos/octo/hello/issues/1"); ok {
+		t.Error("a template of a disabled toolset matched")
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation describes one way in which a value fails its schema.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against the
+// schema and returns every violation found, in a stable order.
+func (s *Schema) Validate(value interface{}) []Violation {
+	var violations []Violation
+	s.validate("", value, &violations)
+	return violations
+}
+
+// omitNulls removes the nullable properties that are null from a value
+// decoded with json.Decoder.UseNumber, so they count as omitted. It
+// reports whether anything was removed.
+func (s *Schema) omitNulls(value interface{}) bool {
+	removed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, propValue := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				continue
+			}
+			if propValue == nil && prop.nullable {
+				delete(v, name)
+				removed = true
+				continue
+			}
+			if prop.omitNulls(propValue) {
+				removed = true
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for _, item := range v {
+				if s.Items.omitNulls(item) {
+					removed = true
+				}
+			}
+		}
+	}
+	return removed
+}
+
+func (s *Schema) validate(path string, value interface{}, violations *[]Violation) {
+	reportAt := func(where, format string, args ...interface{}) {
+		if where == "" {
+			where = "arguments"
+		}
+		*violations = append(*violations, Violation{Path: where, Message: fmt.Sprintf(format, args...)})
+	}
+	report := func(format string, args ...interface{}) {
+		reportAt(path, format, args...)
+	}
+
+	if s.Type != "" && !matchesType(s.Type, value) {
+		report("expected %s, got %s", s.Type, jsonTypeName(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		report("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				reportAt(joinPath(path, name), "is required")
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					reportAt(joinPath(path, name), "is not a known property")
+				}
+				continue
+			}
+			prop.validate(joinPath(path, name), v[name], violations)
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			report("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			report("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("must match pattern %s", s.Pattern)
+		}
+
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			report("invalid number %s", v)
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			report("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			report("must be <= %v", *s.Maximum)
+		}
+	}
+}
+
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		// Integral literals such as 2.0 or 1e2 are rejected too: arguments
+		// are decoded into Go integers, which accept only integer literals.
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, v := range enum {
+		parts[i] = fmt.Sprint(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
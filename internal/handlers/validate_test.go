@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// decodeArgs decodes raw the way tools decode their arguments.
+func decodeArgs(t *testing.T, raw string) interface{} {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	type item struct {
+		Name string `json:"name" jsonschema:"required"`
+	}
+	schema := schemaFor(reflect.TypeOf(struct {
+		Owner  string   `json:"owner" jsonschema:"required"`
+		State  string   `json:"state" jsonschema:"enum=open|closed"`
+		Branch string   `json:"branch" jsonschema:"minLength=2,maxLength=4,pattern=^[a-z]+$"`
+		Draft  bool     `json:"draft"`
+		Ratio  float64  `json:"ratio" jsonschema:"maximum=1"`
+		Labels []string `json:"labels"`
+		Items  []item   `json:"items"`
+	}{}))
+
+	tests := []struct {
+		name string
+		args string
+		want []Violation
+	}{
+		{"valid", `{"owner":"octo","state":"open","branch":"main","draft":true,"ratio":0.5,"labels":["bug"],"items":[{"name":"a"}]}`, nil},
+		{"not an object", `[]`, []Violation{{Path: "arguments", Message: "expected object, got array"}}},
+		{"missing required", `{}`, []Violation{{Path: "owner", Message: "is required"}}},
+		{"unknown property", `{"owner":"octo","extra":1}`, []Violation{{Path: "extra", Message: "is not a known property"}}},
+		{"not in enum", `{"owner":"octo","state":"merged"}`, []Violation{{Path: "state", Message: "must be one of [open, closed]"}}},
+		{"too short", `{"owner":"octo","branch":"a"}`, []Violation{{Path: "branch", Message: "must be at least 2 characters"}}},
+		{"too long and off pattern", `{"owner":"octo","branch":"MAIN1"}`, []Violation{
+			{Path: "branch", Message: "must be at most 4 characters"},
+			{Path: "branch", Message: "must match pattern ^[a-z]+$"},
+		}},
+		{"wrong types", `{"owner":1,"draft":"yes","labels":"bug"}`, []Violation{
+			{Path: "draft", Message: "expected boolean, got string"},
+			{Path: "labels", Message: "expected array, got string"},
+			{Path: "owner", Message: "expected string, got number"},
+		}},
+		{"above maximum", `{"owner":"octo","ratio":1.5}`, []Violation{{Path: "ratio", Message: "must be <= 1"}}},
+		{"null", `{"owner":null}`, []Violation{{Path: "owner", Message: "expected string, got null"}}},
+		{"nested", `{"owner":"octo","labels":[1],"items":[{}]}`, []Violation{
+			{Path: "items[0].name", Message: "is required"},
+			{Path: "labels[0]", Message: "expected string, got number"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := schema.Validate(decodeArgs(t, tt.args)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Validate(%s) = %v, want %v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestValidateIntegers(t *testing.T) {
+	schema := schemaFor(reflect.TypeOf(struct {
+		N int `json:"n" jsonschema:"minimum=1"`
+	}{}))
+
+	tests := []struct {
+		args string
+		want []Violation
+	}{
+		{`{"n":2}`, nil},
+		{`{"n":-1}`, []Violation{{Path: "n", Message: "must be >= 1"}}},
+		{`{"n":2.0}`, []Violation{{Path: "n", Message: "expected integer, got number"}}},
+		{`{"n":1e2}`, []Violation{{Path: "n", Message: "expected integer, got number"}}},
+		{`{"n":2.5}`, []Violation{{Path: "n", Message: "expected integer, got number"}}},
+		{`{"n":99999999999999999999}`, []Violation{{Path: "n", Message: "expected integer, got number"}}},
+		{`{"n":"2"}`, []Violation{{Path: "n", Message: "expected integer, got string"}}},
+	}
+	for _, tt := range tests {
+		if got := schema.Validate(decodeArgs(t, tt.args)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Validate(%s) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+// TestValidArgumentsDecode checks that arguments passing validation also
+// decode, so no valid call fails as an internal error.
+func TestValidArgumentsDecode(t *testing.T) {
+	tool := NewTool("t", "", true, func(ctx context.Context, args struct {
+		N int `json:"n"`
+	}) (*ToolResult, error) {
+		return textResult("ok"), nil
+	})
+
+	for _, raw := range []string{`{"n":2}`, `{"n":2.0}`, `{"n":1e2}`} {
+		_, err := tool.Call(context.Background(), json.RawMessage(raw))
+		var argErr *ArgumentError
+		if err != nil && (!errors.As(err, &argErr) || argErr.Violations == nil) {
+			t.Errorf("Call(%s) passed validation but failed to decode: %v", raw, err)
+		}
+	}
+}
+
+func TestNullOptionalArguments(t *testing.T) {
+	type args struct {
+		Owner string `json:"owner" jsonschema:"required"`
+		Name  string `json:"name"`
+		Page  *int   `json:"page" jsonschema:"default=3"`
+		Limit int    `json:"limit,omitempty"`
+	}
+	var got args
+	tool := NewTool("t", "", true, func(ctx context.Context, a args) (*ToolResult, error) {
+		got = a
+		return textResult("ok"), nil
+	})
+
+	tests := []struct {
+		args string
+		want []Violation
+	}{
+		{`{"owner":"octo","page":null,"limit":null}`, nil},
+		{`{"owner":"octo","name":null}`, []Violation{{Path: "name", Message: "expected string, got null"}}},
+		{`{"owner":null}`, []Violation{{Path: "owner", Message: "expected string, got null"}}},
+	}
+	for _, tt := range tests {
+		got = args{}
+		_, err := tool.Call(context.Background(), json.RawMessage(tt.args))
+		var argErr *ArgumentError
+		errors.As(err, &argErr)
+		switch {
+		case tt.want == nil && err != nil:
+			t.Errorf("Call(%s): %v", tt.args, err)
+		case tt.want == nil && (got.Page == nil || *got.Page != 3):
+			t.Errorf("Call(%s): page = %v, want the default", tt.args, got.Page)
+		case tt.want != nil && (argErr == nil || !reflect.DeepEqual(argErr.Violations, tt.want)):
+			t.Errorf("Call(%s) = %v, want violations %v", tt.args, err, tt.want)
+		}
+	}
+}
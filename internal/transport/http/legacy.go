@@ -0,0 +1,74 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/github-mcp-http/pkg/sse"
+)
+
+// messagesPath is where legacy SSE clients POST their JSON-RPC messages.
+const messagesPath = "/api/v1/messages"
+
+// handleLegacySSE implements the 2024-11-05 HTTP+SSE transport: the GET
+// creates the session and announces the message endpoint, and every
+// JSON-RPC response is delivered over this stream.
+func (s *Server) handleLegacySSE(w http.ResponseWriter, r *http.Request) {
+	session, err := s.createSession(r)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create session")
+		s.writeError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	client := s.openEventStream(w, session, "")
+
+	endpoint := messagesPath + "?" + url.Values{"sessionId": {session.ID}}.Encode()
+	if err := s.sseHub.SendTo(session.ID, sse.Event{Type: "endpoint", Data: endpoint}); err != nil {
+		s.logger.WithError(err).Error("Failed to send endpoint event")
+		s.closeSession(session.ID)
+		return
+	}
+
+	s.serveEvents(r, session, client)
+	s.closeSession(session.ID)
+}
+
+// handleMessages accepts a JSON-RPC message for a legacy SSE session. The
+// POST is only acknowledged; the response travels over the event stream.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		s.writeError(w, http.StatusBadRequest, "Missing session ID")
+		return
+	}
+
+	session, exists := s.lookupSession(r, sessionID)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	if session.EventClient() == nil {
+		s.writeError(w, http.StatusConflict, "Session has no event stream")
+		return
+	}
+	session.touch()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	response := s.mcpHandler.ProcessRPC(session.Context, body)
+
+	if response != nil {
+		if err := s.sseHub.SendTo(session.ID, sse.Event{Type: "message", Data: response}); err != nil {
+			s.writeError(w, http.StatusGone, "Event stream closed")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/github-mcp-http/internal/auth"
+	"github.com/github-mcp-http/internal/githubauth"
 	"github.com/github-mcp-http/internal/handlers"
 	"github.com/github-mcp-http/pkg/sse"
 	"github.com/gorilla/mux"
@@ -20,8 +25,40 @@ type ServerConfig struct {
 	Port        int
 	TLSCert     string
 	TLSKey      string
+	// GitHubToken is used by sessions that connect without a token of
+	// their own in the X-GitHub-Token header. When empty, every session
+	// must bring one.
 	GitHubToken string
-	ReadOnly    bool
+	// GitHubApp, when set, serves those sessions instead of GitHubToken.
+	GitHubApp *githubauth.App
+	ReadOnly  bool
+	MaxPages  int
+	// PollInterval is how often subscribed resources are checked for
+	// changes.
+	PollInterval time.Duration
+	// SSE sets buffering and the slow client policy for event streams.
+	SSE sse.Options
+	// BindSessionIP and BindSessionUserAgent additionally bind a session to
+	// the client address and User-Agent that created it.
+	BindSessionIP        bool
+	BindSessionUserAgent bool
+	// TrustProxy takes the client address from X-Forwarded-For, as
+	// recorded by the outermost of TrustedProxies proxies in front of the
+	// server. TrustedProxies below one means one.
+	TrustProxy     bool
+	TrustedProxies int
+	// Authenticator authenticates every request but the health check and
+	// OAuth endpoints. When nil the server is open to anyone who can reach
+	// it.
+	Authenticator auth.Authenticator
+	// ProtectedResource, when set, is published so OAuth clients can find
+	// the authorization server.
+	ProtectedResource *auth.ProtectedResource
+	// AuthorizationServer, when set, is served in-process.
+	AuthorizationServer *auth.AuthorizationServer
+	// GitHubLogin, when set, lets authenticated clients without a GitHub
+	// token sign in to GitHub.
+	GitHubLogin *githubauth.Login
 }
 
 type Server struct {
@@ -31,31 +68,85 @@ type Server struct {
 	mcpHandler  *handlers.MCPHandler
 	sseHub      *sse.Hub
 	sessions    sync.Map
+	sessionKey  []byte
 	logger      *logrus.Logger
 }
 
 type Session struct {
-	ID         string
-	Client     *sse.Client
-	Context    context.Context
-	Cancel     context.CancelFunc
-	LastActive time.Time
+	ID      string
+	State   *handlers.SessionState
+	Context context.Context
+	Cancel  context.CancelFunc
+	// Principal is the client that created the session, or nil when the
+	// server does not authenticate.
+	Principal *auth.Principal
+
+	binding sessionBinding
+
+	// lastActive is the time of the session's last request in unix
+	// nanoseconds, written by concurrent requests and read by cleanup.
+	lastActive atomic.Int64
+	// streams counts the session's open event streams, which keep it
+	// alive however long ago its last request was.
+	streams atomic.Int32
+
+	// mu guards client, which is set when an event stream opens while
+	// requests and session cleanup may be reading it.
+	mu     sync.Mutex
+	client *sse.Client
+}
+
+// EventClient returns the hub client of the session's event stream, or nil
+// if no stream has been opened.
+func (s *Session) EventClient() *sse.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// touch records activity on the session.
+func (s *Session) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// idle reports how long the session has been inactive at now.
+func (s *Session) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, s.lastActive.Load()))
+}
+
+func (s *Session) setEventClient(client *sse.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = client
 }
 
 func NewServer(config *ServerConfig) (*Server, error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	mcpHandler, err := handlers.NewMCPHandler(config.GitHubToken, config.ReadOnly)
+	mcpHandler, err := handlers.NewMCPHandler(handlers.Config{
+		GitHubToken:  config.GitHubToken,
+		ReadOnly:     config.ReadOnly,
+		MaxPages:     config.MaxPages,
+		PollInterval: config.PollInterval,
+		GitHubApp:    config.GitHubApp,
+		GitHubLogin:  config.GitHubLogin,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MCP handler: %w", err)
 	}
 
+	sessionKey, err := newSessionKey()
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Server{
 		config:     config,
 		router:     mux.NewRouter(),
 		mcpHandler: mcpHandler,
-		sseHub:     sse.NewHub(),
+		sseHub:     sse.NewHubWithOptions(config.SSE),
+		sessionKey: sessionKey,
 		logger:     logger,
 	}
 
@@ -73,12 +164,35 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/disconnect", s.handleDisconnect).Methods("POST")
 	api.HandleFunc("/rpc", s.handleRPC).Methods("POST")
 	api.HandleFunc("/events", s.handleSSE).Methods("GET")
+	api.HandleFunc("/messages", s.handleMessages).Methods("POST")
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
+
+	s.router.HandleFunc("/mcp", s.handleMCPPost).Methods("POST")
+	s.router.HandleFunc("/mcp", s.handleMCPGet).Methods("GET")
+	s.router.HandleFunc("/mcp", s.handleMCPDelete).Methods("DELETE")
+
+	if resource := s.config.ProtectedResource; resource != nil {
+		s.router.Handle(auth.ProtectedResourcePath, resource).Methods("GET")
+		// Clients that resolve metadata relative to the MCP endpoint.
+		s.router.Handle(auth.ProtectedResourcePath+"/mcp", resource).Methods("GET")
+	}
+	if as := s.config.AuthorizationServer; as != nil {
+		s.router.HandleFunc(auth.AuthorizationServerMetadataPath, as.ServeMetadata).Methods("GET")
+		s.router.HandleFunc(auth.AuthorizePath, as.HandleAuthorize).Methods("GET", "POST")
+		s.router.HandleFunc(auth.TokenPath, as.HandleToken).Methods("POST")
+		s.router.HandleFunc(auth.RegisterPath, as.HandleRegister).Methods("POST")
+		s.router.HandleFunc(auth.IntrospectPath, as.HandleIntrospect).Methods("POST")
+	}
+	if login := s.config.GitHubLogin; login != nil && login.WebFlowEnabled() {
+		s.router.HandleFunc(githubauth.StartPath, login.HandleStart).Methods("GET", "POST")
+		s.router.HandleFunc(githubauth.CallbackPath, login.HandleCallback).Methods("GET")
+	}
 	
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{mcpSessionHeader},
 		AllowCredentials: true,
 	})
 	
@@ -97,6 +211,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SetReadOnly switches read-only mode while the server runs. Connected
+// clients are notified that their tool list changed.
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.mcpHandler.SetReadOnly(readOnly)
+}
+
 func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ClientInfo struct {
@@ -110,26 +230,22 @@ func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionID := generateSessionID()
-	ctx, cancel := context.WithCancel(context.Background())
-	
-	session := &Session{
-		ID:         sessionID,
-		Context:    ctx,
-		Cancel:     cancel,
-		LastActive: time.Now(),
+	session, err := s.createSession(r)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create session")
+		s.writeError(w, http.StatusInternalServerError, "Failed to create session")
+		return
 	}
-	
-	s.sessions.Store(sessionID, session)
 
-	initResult, err := s.mcpHandler.Initialize(ctx, req.ClientInfo.Name, req.ClientInfo.Version)
+	initResult, err := s.mcpHandler.Initialize(session.Context, req.ClientInfo.Name, req.ClientInfo.Version)
 	if err != nil {
+		s.closeSession(session.ID)
 		s.writeError(w, http.StatusInternalServerError, "Failed to initialize MCP connection")
 		return
 	}
 
 	response := map[string]interface{}{
-		"sessionId":    sessionID,
+		"sessionId":    session.ID,
 		"serverInfo":   initResult.ServerInfo,
 		"capabilities": initResult.Capabilities,
 	}
@@ -145,12 +261,8 @@ func (s *Server) handleDisconnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if session, ok := s.sessions.LoadAndDelete(sessionID); ok {
-		sess := session.(*Session)
-		sess.Cancel()
-		if sess.Client != nil {
-			s.sseHub.Unregister(sess.Client)
-		}
+	if _, exists := s.lookupSession(r, sessionID); exists {
+		s.closeSession(sessionID)
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -163,25 +275,25 @@ func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionVal, exists := s.sessions.Load(sessionID)
+	session, exists := s.lookupSession(r, sessionID)
 	if !exists {
 		s.writeError(w, http.StatusUnauthorized, "Invalid session")
 		return
 	}
 
-	session := sessionVal.(*Session)
-	session.LastActive = time.Now()
+	session.touch()
 
-	var rpcReq json.RawMessage
-	if err := json.NewDecoder(r.Body).Decode(&rpcReq); err != nil {
+	// Malformed JSON is reported by ProcessRPC as a JSON-RPC parse error.
+	rpcReq, err := io.ReadAll(r.Body)
+	if err != nil {
 		s.writeError(w, http.StatusBadRequest, "Invalid RPC request")
 		return
 	}
 
-	response, err := s.mcpHandler.ProcessRPC(session.Context, rpcReq)
-	if err != nil {
-		s.logger.WithError(err).Error("RPC processing failed")
-		s.writeError(w, http.StatusInternalServerError, "RPC processing failed")
+	response := s.mcpHandler.ProcessRPC(session.Context, rpcReq)
+
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
@@ -192,46 +304,75 @@ func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	sessionID := r.Header.Get("X-Session-ID")
 	if sessionID == "" {
-		s.writeError(w, http.StatusBadRequest, "Missing session ID")
+		// Without a prior /connect this is a standard MCP SSE client.
+		s.handleLegacySSE(w, r)
 		return
 	}
 
-	sessionVal, exists := s.sessions.Load(sessionID)
+	session, exists := s.lookupSession(r, sessionID)
 	if !exists {
 		s.writeError(w, http.StatusUnauthorized, "Invalid session")
 		return
 	}
 
-	session := sessionVal.(*Session)
-	
-	client := sse.NewClient(sessionID, w)
-	session.Client = client
-	s.sseHub.Register(client)
+	client := s.openEventStream(w, session, r.Header.Get("Last-Event-ID"))
+
+	s.sseHub.SendTo(session.ID, sse.Event{
+		Type: "connected",
+		Data: map[string]string{"sessionId": sessionID},
+	})
+
+	s.serveEvents(r, session, client)
+}
+
+// openEventStream writes the SSE response headers and attaches a new hub
+// client to the session. A client reconnecting with the ID of the last
+// event it saw is first sent everything it missed.
+func (s *Server) openEventStream(w http.ResponseWriter, session *Session, lastEventID string) *sse.Client {
+	// Streams outlive the server-wide WriteTimeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	client := s.sseHub.NewClient(session.ID, w)
+	session.setEventClient(client)
+	if !s.sseHub.Resume(client, lastEventID) {
+		s.logger.WithFields(logrus.Fields{
+			"sessionId":   session.ID,
+			"lastEventId": lastEventID,
+		}).Warn("Events since Last-Event-ID are no longer available")
+	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
 
-	client.Send(sse.Event{
-		Type: "connected",
-		Data: map[string]string{"sessionId": sessionID},
-	})
-
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	return client
+}
 
-	for {
+// serveEvents delivers the session's events to the client, pinging it to
+// keep the connection alive, until the session, the request or the stream
+// ends.
+func (s *Server) serveEvents(r *http.Request, session *Session, client *sse.Client) {
+	session.streams.Add(1)
+	defer func() {
+		session.touch()
+		session.streams.Add(-1)
+	}()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
 		select {
 		case <-session.Context.Done():
-			return
-		case <-r.Context().Done():
-			s.sseHub.Unregister(client)
-			return
-		case <-ticker.C:
-			client.Send(sse.Event{Type: "ping"})
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+
+	if err := client.Serve(ctx, 30*time.Second); err != nil && ctx.Err() == nil {
+		s.logger.WithError(err).WithField("sessionId", session.ID).Debug("Event stream failed")
 	}
+	s.sseHub.Unregister(client)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -239,6 +380,10 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"status":  "healthy",
 		"time":    time.Now().UTC(),
 		"version": "1.0.0",
+		"sse": map[string]interface{}{
+			"clients":        s.sseHub.Clients(),
+			"dropped_events": s.sseHub.Dropped(),
+		},
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -262,15 +407,130 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// authMiddleware rejects unauthenticated requests with 401 and records the
+// principal of the rest in their context.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/v1/health" {
+		if isPublicPath(r.URL.Path) || s.config.Authenticator == nil {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		principal, err := s.config.Authenticator.Authenticate(r)
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+			}).Warn("Authentication failed")
+			w.Header().Set("WWW-Authenticate", s.config.Authenticator.Challenge())
+			s.writeError(w, http.StatusUnauthorized, "Authentication required: "+err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// isPublicPath reports whether path is served without authentication: the
+// health check, and everything a client needs to obtain credentials.
+func isPublicPath(path string) bool {
+	return path == "/api/v1/health" ||
+		strings.HasPrefix(path, "/.well-known/") ||
+		strings.HasPrefix(path, "/oauth/") ||
+		path == githubauth.StartPath ||
+		path == githubauth.CallbackPath
+}
+
+// createSession starts a session bound to the client that sent r.
+func (s *Server) createSession(r *http.Request) (*Session, error) {
+	id, err := s.generateSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	state := handlers.NewSessionState()
+	principal, authenticated := auth.PrincipalFromContext(r.Context())
+	if authenticated {
+		state.SetPrincipal(principal.String())
+	}
+	if token := r.Header.Get(githubTokenHeader); token != "" {
+		s.mcpHandler.SetGitHubToken(state, token)
+	} else {
+		s.mcpHandler.RestoreGitHubLogin(state)
+	}
+	ctx, cancel := context.WithCancel(handlers.WithSession(context.Background(), state))
+
+	session := &Session{
+		ID:      id,
+		State:   state,
+		Context: ctx,
+		Cancel:  cancel,
+		binding: s.bindingFor(r),
+	}
+	session.touch()
+	if authenticated {
+		session.Principal = principal
+		s.logger.WithFields(logrus.Fields{
+			"principal":   principal.String(),
+			"remote_addr": r.RemoteAddr,
+		}).Info("Session created")
+	}
+	state.SetNotifier(func(message interface{}) {
+		s.notifySession(session, message)
 	})
+
+	s.sessions.Store(session.ID, session)
+	return session, nil
+}
+
+// lookupSession returns the session sessionID names if r may use it. A
+// forged ID and a session bound to another client are both reported as
+// unknown.
+func (s *Server) lookupSession(r *http.Request, sessionID string) (*Session, bool) {
+	if !s.validSessionID(sessionID) {
+		return nil, false
+	}
+
+	sessionVal, exists := s.sessions.Load(sessionID)
+	if !exists {
+		return nil, false
+	}
+
+	session := sessionVal.(*Session)
+	if session.binding != s.bindingFor(r) {
+		s.logger.WithFields(logrus.Fields{
+			"remote_addr": r.RemoteAddr,
+			"path":        r.URL.Path,
+		}).Warn("Session used by a client it is not bound to")
+		return nil, false
+	}
+	return session, true
+}
+
+// closeSession cancels and forgets a session. It reports whether the session
+// existed.
+func (s *Server) closeSession(sessionID string) bool {
+	sessionVal, ok := s.sessions.LoadAndDelete(sessionID)
+	if !ok {
+		return false
+	}
+
+	session := sessionVal.(*Session)
+	session.Cancel()
+	s.mcpHandler.CloseSession(session.State)
+	if client := session.EventClient(); client != nil {
+		s.sseHub.Unregister(client)
+	}
+	s.sseHub.Forget(session.ID)
+	return true
+}
+
+// notifySession pushes a server-initiated message over the session's event
+// stream. Sessions without an open stream cannot receive it.
+func (s *Server) notifySession(session *Session, message interface{}) {
+	if err := s.sseHub.SendTo(session.ID, sse.Event{Type: "message", Data: message}); err != nil {
+		s.logger.WithError(err).WithField("sessionId", session.ID).Debug("Failed to deliver notification")
+	}
 }
 
 func (s *Server) writeError(w http.ResponseWriter, code int, message string) {
@@ -285,23 +545,24 @@ func (s *Server) cleanupSessions() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		now := time.Now()
-		s.sessions.Range(func(key, value interface{}) bool {
-			session := value.(*Session)
-			if now.Sub(session.LastActive) > 30*time.Minute {
-				s.logger.WithField("sessionId", session.ID).Info("Cleaning up inactive session")
-				session.Cancel()
-				if session.Client != nil {
-					s.sseHub.Unregister(session.Client)
-				}
-				s.sessions.Delete(key)
-			}
-			return true
-		})
+	for now := range ticker.C {
+		s.expireSessions(now)
 	}
 }
 
+// expireSessions closes the sessions that have neither made a request in
+// the last 30 minutes nor have an event stream open.
+func (s *Server) expireSessions(now time.Time) {
+	s.sessions.Range(func(key, value interface{}) bool {
+		session := value.(*Session)
+		if session.streams.Load() == 0 && session.idle(now) > 30*time.Minute {
+			s.logger.WithField("sessionId", session.ID).Info("Cleaning up inactive session")
+			s.closeSession(session.ID)
+		}
+		return true
+	})
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -312,6 +573,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-func generateSessionID() string {
-	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), time.Now().Unix())
-}
\ No newline at end of file
+// Flush lets event streams flush through the logging wrapper.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
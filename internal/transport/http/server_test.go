@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEventStreamOpensWhileSessionIsUsed opens an event stream while
+// messages arrive for the session and the session is closed, which the race
+// detector checks.
+func TestEventStreamOpensWhileSessionIsUsed(t *testing.T) {
+	server := httptest.NewServer(newTestServer(t, &ServerConfig{}))
+	defer server.Close()
+
+	for i := 0; i < 20; i++ {
+		resp, err := http.Post(server.URL+"/api/v1/connect", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var connected struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.NewDecoder(resp.Body).Decode(&connected)
+		resp.Body.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events", nil)
+			req.Header.Set("X-Session-ID", connected.SessionID)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			body := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+			for j := 0; j < 5; j++ {
+				if resp, err := http.Post(server.URL+"/api/v1/messages?sessionId="+connected.SessionID, "application/json", strings.NewReader(body)); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/disconnect", nil)
+			req.Header.Set("X-Session-ID", connected.SessionID)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/github-mcp-http/internal/auth"
+)
+
+// githubTokenHeader carries the GitHub token a session acts with, sent with
+// the request that creates the session.
+const githubTokenHeader = "X-GitHub-Token"
+
+// A session ID is the only credential a client presents after the session
+// is created, so it is 128 random bits followed by a truncated HMAC of
+// them. IDs that were not issued by this process are rejected before any
+// lookup.
+const (
+	sessionNonceSize = 16
+	sessionMACSize   = 16
+)
+
+// newSessionKey returns a random key for signing session IDs. Sessions live
+// in memory, so the key never needs to outlive the process.
+func newSessionKey() ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *Server) generateSessionID() (string, error) {
+	id := make([]byte, sessionNonceSize, sessionNonceSize+sessionMACSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	id = append(id, s.sessionMAC(id)...)
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// validSessionID reports whether sessionID carries a valid signature.
+func (s *Server) validSessionID(sessionID string) bool {
+	id, err := base64.RawURLEncoding.DecodeString(sessionID)
+	if err != nil || len(id) != sessionNonceSize+sessionMACSize {
+		return false
+	}
+	return hmac.Equal(id[sessionNonceSize:], s.sessionMAC(id[:sessionNonceSize]))
+}
+
+func (s *Server) sessionMAC(nonce []byte) []byte {
+	mac := hmac.New(sha256.New, s.sessionKey)
+	mac.Write(nonce)
+	return mac.Sum(nil)[:sessionMACSize]
+}
+
+// sessionBinding is what a request must match to use a session, so that a
+// leaked session ID cannot be used by another principal or, optionally,
+// from another address or client.
+type sessionBinding struct {
+	principal string
+	ip        string
+	userAgent string
+}
+
+func (s *Server) bindingFor(r *http.Request) sessionBinding {
+	var binding sessionBinding
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		binding.principal = principal.String()
+	}
+	if s.config.BindSessionIP {
+		binding.ip = s.clientIP(r)
+	}
+	if s.config.BindSessionUserAgent {
+		binding.userAgent = r.UserAgent()
+	}
+	return binding
+}
+
+// clientIP returns the address of the client that sent r. Behind trusted
+// proxies this is the address the outermost of them recorded in
+// X-Forwarded-For. Each proxy appends the address it was reached from, so
+// that entry is found counting from the right; entries further left came
+// from the client and prove nothing.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.config.TrustProxy {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					hops = append(hops, hop)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			proxies := s.config.TrustedProxies
+			if proxies < 1 {
+				proxies = 1
+			}
+			if proxies > len(hops) {
+				proxies = len(hops)
+			}
+			return hops[len(hops)-proxies]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/github-mcp-http/internal/auth"
+)
+
+func newTestServer(t *testing.T, config *ServerConfig) *Server {
+	t.Helper()
+	s, err := NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.logger.SetOutput(io.Discard)
+	return s
+}
+
+func TestSessionIDSignatures(t *testing.T) {
+	s := newTestServer(t, &ServerConfig{})
+	other := newTestServer(t, &ServerConfig{})
+
+	id, err := s.generateSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := other.generateSessionID()
+	raw, _ := base64.RawURLEncoding.DecodeString(id)
+	flipped := append([]byte(nil), raw...)
+	flipped[0] ^= 1
+
+	tests := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{"issued", id, true},
+		{"issued by another server", foreign, false},
+		{"altered nonce", base64.RawURLEncoding.EncodeToString(flipped), false},
+		{"truncated", id[:len(id)-2], false},
+		{"not base64", "!" + id[1:], false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := s.validSessionID(tt.id); got != tt.valid {
+			t.Errorf("%s: validSessionID = %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+}
+
+func TestSessionBinding(t *testing.T) {
+	s := newTestServer(t, &ServerConfig{BindSessionIP: true, BindSessionUserAgent: true})
+
+	request := func(principal, ip, userAgent string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		r.RemoteAddr = ip + ":1234"
+		r.Header.Set("User-Agent", userAgent)
+		if principal != "" {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: principal, Method: "api_key"}))
+		}
+		return r
+	}
+	session, err := s.createSession(request("alice", "192.0.2.1", "client/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		ok   bool
+	}{
+		{"same client", request("alice", "192.0.2.1", "client/1"), true},
+		{"other principal", request("bob", "192.0.2.1", "client/1"), false},
+		{"unauthenticated", request("", "192.0.2.1", "client/1"), false},
+		{"other address", request("alice", "198.51.100.7", "client/1"), false},
+		{"other user agent", request("alice", "192.0.2.1", "client/2"), false},
+	}
+	for _, tt := range tests {
+		if _, ok := s.lookupSession(tt.r, session.ID); ok != tt.ok {
+			t.Errorf("%s: lookupSession = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		proxies    int
+		forwarded  []string
+		want       string
+	}{
+		{"direct", false, 0, nil, "192.0.2.1"},
+		{"forwarded header ignored", false, 0, []string{"203.0.113.9"}, "192.0.2.1"},
+		{"no header behind proxy", true, 1, nil, "192.0.2.1"},
+		{"one proxy", true, 1, []string{"203.0.113.9"}, "203.0.113.9"},
+		{"one proxy ignores spoofed entries", true, 1, []string{"198.51.100.7, 203.0.113.9"}, "203.0.113.9"},
+		{"zero proxies means one", true, 0, []string{"198.51.100.7, 203.0.113.9"}, "203.0.113.9"},
+		{"two proxies", true, 2, []string{"198.51.100.7, 203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"repeated headers", true, 2, []string{"198.51.100.7", "203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"fewer hops than proxies", true, 3, []string{"203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: &ServerConfig{TrustProxy: tt.trustProxy, TrustedProxies: tt.proxies}}
+			r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			r.RemoteAddr = "192.0.2.1:4321"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := s.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	s := newTestServer(t, &ServerConfig{})
+	session, err := s.createSession(httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+
+	// A listening client keeps the session however long it stays quiet.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	client := s.openEventStream(w, session, "")
+	done := make(chan struct{})
+	go func() {
+		s.serveEvents(r.WithContext(ctx), session, client)
+		close(done)
+	}()
+	for session.streams.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.expireSessions(later)
+	if _, ok := s.sessions.Load(session.ID); !ok {
+		t.Fatal("session with an open event stream expired")
+	}
+
+	cancel()
+	<-done
+	s.expireSessions(later)
+	if _, ok := s.sessions.Load(session.ID); ok {
+		t.Error("idle session without an event stream did not expire")
+	}
+}
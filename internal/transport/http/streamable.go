@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/github-mcp-http/pkg/sse"
+)
+
+// mcpSessionHeader carries the session ID for the Streamable HTTP transport.
+const mcpSessionHeader = "Mcp-Session-Id"
+
+// handleMCPPost accepts a JSON-RPC message or batch on the Streamable HTTP
+// endpoint. Requests are answered with either a JSON body or a one-shot SSE
+// stream, depending on what the client accepts; input that needs no reply
+// is acknowledged with 202 Accepted.
+func (s *Server) handleMCPPost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var msg struct {
+		Method string `json:"method"`
+	}
+	// An initialize for an existing session goes to that session, which
+	// refuses it, rather than starting a second one.
+	if json.Unmarshal(body, &msg) == nil && msg.Method == "initialize" && r.Header.Get(mcpSessionHeader) == "" {
+		s.handleMCPInitialize(w, r, body)
+		return
+	}
+
+	session, ok := s.mcpSession(w, r)
+	if !ok {
+		return
+	}
+	session.touch()
+
+	response := s.mcpHandler.ProcessRPC(session.Context, body)
+
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	s.writeMCPResponse(w, r, session.ID, response)
+}
+
+// handleMCPInitialize creates the session that an initialize request
+// starts. The session is discarded again if the handshake fails.
+func (s *Server) handleMCPInitialize(w http.ResponseWriter, r *http.Request, body []byte) {
+	session, err := s.createSession(r)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create session")
+		s.writeError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+
+	response := s.mcpHandler.ProcessRPC(session.Context, body)
+
+	if !session.State.Initialized() {
+		s.closeSession(session.ID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	s.writeMCPResponse(w, r, session.ID, response)
+}
+
+// handleMCPGet opens a server-initiated SSE stream for an existing session.
+func (s *Server) handleMCPGet(w http.ResponseWriter, r *http.Request) {
+	if !accepts(r, "text/event-stream") {
+		s.writeError(w, http.StatusNotAcceptable, "Client must accept text/event-stream")
+		return
+	}
+
+	session, ok := s.mcpSession(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set(mcpSessionHeader, session.ID)
+	client := s.openEventStream(w, session, r.Header.Get("Last-Event-ID"))
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	s.serveEvents(r, session, client)
+}
+
+// handleMCPDelete terminates a session at the client's request.
+func (s *Server) handleMCPDelete(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.mcpSession(w, r)
+	if !ok {
+		return
+	}
+
+	s.closeSession(session.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mcpSession resolves the Mcp-Session-Id header, writing the spec-mandated
+// 400 or 404 when it is missing or unknown.
+func (s *Server) mcpSession(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	sessionID := r.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		s.writeError(w, http.StatusBadRequest, "Missing session ID")
+		return nil, false
+	}
+
+	session, exists := s.lookupSession(r, sessionID)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Session not found")
+		return nil, false
+	}
+
+	return session, true
+}
+
+// writeMCPResponse answers a POST with plain JSON unless the client only
+// accepts an event stream, in which case the response is sent as a single
+// SSE message and the stream is closed.
+func (s *Server) writeMCPResponse(w http.ResponseWriter, r *http.Request, sessionID string, response interface{}) {
+	w.Header().Set(mcpSessionHeader, sessionID)
+
+	if accepts(r, "application/json") || !accepts(r, "text/event-stream") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	client := s.sseHub.NewClient(sessionID, w)
+	if err := client.Send(sse.Event{Type: "message", Data: response}); err != nil {
+		s.logger.WithError(err).Error("Failed to write SSE response")
+	}
+}
+
+func accepts(r *http.Request, mediaType string) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			part = strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if part == mediaType || part == "*/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInitializeWithSessionHeader(t *testing.T) {
+	s := newTestServer(t, &ServerConfig{})
+	const initialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`
+
+	post := func(sessionID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			r.Header.Set(mcpSessionHeader, sessionID)
+		}
+		w := httptest.NewRecorder()
+		s.handleMCPPost(w, r)
+		return w
+	}
+
+	first := post("")
+	sessionID := first.Header().Get(mcpSessionHeader)
+	if sessionID == "" {
+		t.Fatalf("initialize created no session: %d %s", first.Code, first.Body)
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		status    int
+		body      string
+	}{
+		{"existing session", sessionID, http.StatusOK, "Session already initialized"},
+		{"unknown session", "unknown", http.StatusNotFound, "Session not found"},
+	}
+	for _, tt := range tests {
+		w := post(tt.sessionID)
+		body, _ := io.ReadAll(w.Body)
+		if w.Code != tt.status || !strings.Contains(string(body), tt.body) {
+			t.Errorf("%s: %d %s, want %d containing %q", tt.name, w.Code, body, tt.status, tt.body)
+		}
+	}
+
+	sessions := 0
+	s.sessions.Range(func(key, value interface{}) bool {
+		sessions++
+		return true
+	})
+	if sessions != 1 {
+		t.Errorf("%d sessions, want 1", sessions)
+	}
+}
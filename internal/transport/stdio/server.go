@@ -0,0 +1,142 @@
+package stdio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/github-mcp-http/internal/githubauth"
+	"github.com/github-mcp-http/internal/handlers"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMessageSize bounds a single newline-delimited JSON-RPC message.
+const maxMessageSize = 10 * 1024 * 1024
+
+type ServerConfig struct {
+	GitHubToken string
+	// GitHubApp, when set, is used instead of GitHubToken.
+	GitHubApp *githubauth.App
+	ReadOnly  bool
+	MaxPages  int
+	// PollInterval is how often subscribed resources are checked for
+	// changes.
+	PollInterval time.Duration
+}
+
+type Server struct {
+	config     *ServerConfig
+	mcpHandler *handlers.MCPHandler
+	state      *handlers.SessionState
+	logger     *logrus.Logger
+	out        io.Writer
+	mu         sync.Mutex
+}
+
+func NewServer(config *ServerConfig) (*Server, error) {
+	// stdout carries the protocol, so logs must never go there.
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(os.Stderr)
+
+	mcpHandler, err := handlers.NewMCPHandler(handlers.Config{
+		GitHubToken:  config.GitHubToken,
+		GitHubApp:    config.GitHubApp,
+		ReadOnly:     config.ReadOnly,
+		MaxPages:     config.MaxPages,
+		PollInterval: config.PollInterval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP handler: %w", err)
+	}
+
+	return &Server{
+		config:     config,
+		mcpHandler: mcpHandler,
+		state:      handlers.NewSessionState(),
+		logger:     logger,
+	}, nil
+}
+
+// SetReadOnly switches read-only mode while the server runs. Connected
+// clients are notified that their tool list changed.
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.mcpHandler.SetReadOnly(readOnly)
+}
+
+// Serve reads newline-delimited JSON-RPC messages from in and writes each
+// response to out on its own line. It returns when in reaches EOF or ctx is
+// cancelled.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = out
+	ctx = handlers.WithSession(ctx, s.state)
+
+	s.state.SetNotifier(func(message interface{}) {
+		if err := s.writeMessage(message); err != nil {
+			s.logger.WithError(err).Error("Failed to write notification")
+		}
+	})
+	defer s.mcpHandler.CloseSession(s.state)
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			s.handleMessage(ctx, line)
+		}
+	}
+}
+
+func (s *Server) handleMessage(ctx context.Context, msg []byte) {
+	response := s.mcpHandler.ProcessRPC(ctx, json.RawMessage(msg))
+	if response == nil {
+		return
+	}
+
+	if err := s.writeMessage(response); err != nil {
+		s.logger.WithError(err).Error("Failed to write response")
+	}
+}
+
+func (s *Server) writeMessage(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
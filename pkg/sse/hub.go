@@ -1,130 +1,643 @@
 package sse
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var (
+	// ErrClientNotFound is returned when no client is registered under an ID.
+	ErrClientNotFound = errors.New("sse: client not found")
+	// ErrClientClosed is returned when sending to a client that has closed.
+	ErrClientClosed = errors.New("sse: client is closed")
+	// ErrBufferFull is returned when a client's event queue has no room.
+	ErrBufferFull = errors.New("sse: client buffer full")
+)
+
+const (
+	DefaultBufferSize   = 256
+	DefaultReplaySize   = 256
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+// Policy decides what happens to an event for a client whose queue is
+// full because it reads slower than events arrive.
+type Policy int
+
+const (
+	// Disconnect closes the slow client. Events keep being recorded, so it
+	// can reconnect with Last-Event-ID and resume.
+	Disconnect Policy = iota
+	// DropOldest discards the oldest queued event to make room.
+	DropOldest
+	// Block holds up to another buffer's worth of events for the client
+	// and hands them over as its writer makes room, disconnecting it if
+	// none appears within the write timeout. The waiting happens on a
+	// goroutine of the client's own, so senders never wait.
+	Block
+)
+
+// ParsePolicy parses a policy name: disconnect, drop-oldest or block.
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "disconnect", "":
+		return Disconnect, nil
+	case "drop-oldest":
+		return DropOldest, nil
+	case "block":
+		return Block, nil
+	default:
+		return Disconnect, fmt.Errorf("sse: unknown slow client policy %q", name)
+	}
+}
+
+func (p Policy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	default:
+		return "disconnect"
+	}
+}
+
+// Options tune how a hub buffers events. Zero fields take the defaults.
+type Options struct {
+	// BufferSize is the number of events queued per client.
+	BufferSize int
+	// ReplaySize is the number of recent events kept per client ID for
+	// replay after a reconnect.
+	ReplaySize int
+	// Policy applies when a client's queue is full.
+	Policy Policy
+	// WriteTimeout bounds each write to a client, and how long the Block
+	// policy waits for room for each held event.
+	WriteTimeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultBufferSize
+	}
+	if o.ReplaySize <= 0 {
+		o.ReplaySize = DefaultReplaySize
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = DefaultWriteTimeout
+	}
+	return o
+}
+
 type Event struct {
 	ID   string      `json:"id,omitempty"`
 	Type string      `json:"type"`
 	Data interface{} `json:"data,omitempty"`
 }
 
+// Client is one open event stream. Events queued for it are written to
+// Response by Serve, which runs on the goroutine that owns the response,
+// so a slow client only ever holds up its own writer.
 type Client struct {
 	ID       string
 	Events   chan Event
 	Response http.ResponseWriter
-	mu       sync.Mutex
-	closed   bool
+
+	opts    Options
+	writeMu sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+	// hubDropped is the hub-wide counter, for clients created by a hub.
+	hubDropped *atomic.Uint64
+
+	// held are the events waiting for room in Events under the Block
+	// policy, oldest first. While there are any, waiting is set and a
+	// waiter goroutine moves them over.
+	heldMu  sync.Mutex
+	held    []Event
+	waiting bool
 }
 
 type Hub struct {
-	clients    map[string]*Client
-	register   chan *Client
-	unregister chan *Client
-	broadcast  chan Event
-	mu         sync.RWMutex
+	opts      Options
+	streams   map[string]*stream
+	topics    map[string]map[string]struct{}
+	broadcast chan Event
+	dropped   atomic.Uint64
+	mu        sync.RWMutex
+}
+
+// stream is the event history of one client ID and its connected client,
+// if any. It outlives the client so that a reconnecting client can resume
+// where it left off. mu orders events per ID without holding the hub lock.
+type stream struct {
+	mu     sync.Mutex
+	client *Client
+	lastID uint64
+	// recent is a ring buffer of the last ReplaySize events; start is
+	// the index of the oldest.
+	recent []Event
+	start  int
+	size   int
 }
 
 func NewHub() *Hub {
+	return NewHubWithOptions(Options{})
+}
+
+func NewHubWithOptions(opts Options) *Hub {
 	return &Hub{
-		clients:    make(map[string]*Client),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan Event),
+		opts:      opts.withDefaults(),
+		streams:   make(map[string]*stream),
+		topics:    make(map[string]map[string]struct{}),
+		broadcast: make(chan Event),
 	}
 }
 
 func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client.ID] = client
-			h.mu.Unlock()
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client.ID]; ok {
-				delete(h.clients, client.ID)
-				client.Close()
-			}
-			h.mu.Unlock()
-
-		case event := <-h.broadcast:
-			h.mu.RLock()
-			for _, client := range h.clients {
-				select {
-				case client.Events <- event:
-				default:
-					go h.Unregister(client)
-				}
-			}
-			h.mu.RUnlock()
+	for event := range h.broadcast {
+		h.mu.RLock()
+		streams := make([]*stream, 0, len(h.streams))
+		for _, st := range h.streams {
+			streams = append(streams, st)
+		}
+		h.mu.RUnlock()
+
+		for _, st := range streams {
+			h.deliver(st, event, true)
 		}
 	}
 }
 
+// NewClient creates a client that follows the hub's buffering options.
+func (h *Hub) NewClient(id string, w http.ResponseWriter) *Client {
+	client := newClient(id, w, h.opts)
+	client.hubDropped = &h.dropped
+	return client
+}
+
+// Register adds a client, replacing and closing any client already
+// registered under the same ID. The client can be sent to as soon as
+// Register returns.
 func (h *Hub) Register(client *Client) {
-	h.register <- client
+	h.Resume(client, "")
+}
+
+// Resume registers a client like Register, first queueing every retained
+// event after lastEventID for it. It reports false if events after
+// lastEventID have already been discarded, or lastEventID is not one the
+// hub issued, in which case the client has missed events.
+func (h *Hub) Resume(client *Client, lastEventID string) bool {
+	h.mu.Lock()
+	st, ok := h.streams[client.ID]
+	if !ok {
+		st = &stream{size: h.opts.ReplaySize}
+		h.streams[client.ID] = st
+	}
+	h.mu.Unlock()
+
+	st.mu.Lock()
+	complete := true
+	if lastEventID != "" {
+		var missed []Event
+		missed, complete = st.since(lastEventID)
+		// Replay no more than the client can queue before it is served.
+		if len(missed) > cap(client.Events) {
+			missed = missed[len(missed)-cap(client.Events):]
+			complete = false
+		}
+		for _, event := range missed {
+			if client.enqueue(event) != nil {
+				complete = false
+			}
+		}
+	}
+	old := st.client
+	st.client = client
+	st.mu.Unlock()
+
+	if old != nil && old != client {
+		old.Close()
+	}
+	return complete
 }
 
+// Unregister detaches a client from its ID, drops its topic subscriptions
+// and closes it. The ID's event history is kept until Forget.
 func (h *Hub) Unregister(client *Client) {
-	h.unregister <- client
+	h.mu.Lock()
+	st := h.streams[client.ID]
+	h.mu.Unlock()
+
+	if st != nil {
+		st.mu.Lock()
+		// A reconnect may have replaced the client under this ID.
+		current := st.client == client
+		if current {
+			st.client = nil
+		}
+		st.mu.Unlock()
+
+		if current {
+			h.unsubscribeAll(client.ID)
+		}
+	}
+
+	client.Close()
+}
+
+// Forget discards the event history kept for a client ID. Call it when
+// the session behind the ID ends and the client can no longer resume.
+func (h *Hub) Forget(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.streams, id)
 }
 
 func (h *Hub) Broadcast(event Event) {
 	h.broadcast <- event
 }
 
+// SendTo queues an event for the client registered under id. The hub
+// assigns the event's ID. While the client is disconnected, events are
+// kept for replay until it resumes or the ID is forgotten.
+func (h *Hub) SendTo(id string, event Event) error {
+	h.mu.RLock()
+	st, ok := h.streams[id]
+	h.mu.RUnlock()
+
+	if !ok {
+		return ErrClientNotFound
+	}
+	return h.deliver(st, event, false)
+}
+
+// deliver numbers an event, records it for replay and queues it for the
+// connected client, if any, applying the slow client policy. When
+// connectedOnly is set the event is dropped for streams without a client.
+func (h *Hub) deliver(st *stream, event Event, connectedOnly bool) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if connectedOnly && st.client == nil {
+		return ErrClientNotFound
+	}
+
+	st.lastID++
+	event.ID = strconv.FormatUint(st.lastID, 10)
+	st.record(event)
+
+	if st.client == nil {
+		return nil
+	}
+
+	err := st.client.enqueue(event)
+	if errors.Is(err, ErrBufferFull) {
+		// The client can resume from the replay buffer once it reconnects.
+		st.client.Close()
+		st.client = nil
+	}
+	return err
+}
+
+func (s *stream) record(event Event) {
+	if len(s.recent) < s.size {
+		s.recent = append(s.recent, event)
+		return
+	}
+	s.recent[s.start] = event
+	s.start = (s.start + 1) % len(s.recent)
+}
+
+// since returns the retained events after lastEventID, oldest first, and
+// whether they are all the events issued since then.
+func (s *stream) since(lastEventID string) ([]Event, bool) {
+	after, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil || after > s.lastID {
+		return nil, false
+	}
+
+	missed := int(s.lastID - after)
+	complete := missed <= len(s.recent)
+	if !complete {
+		missed = len(s.recent)
+	}
+
+	events := make([]Event, 0, missed)
+	for i := len(s.recent) - missed; i < len(s.recent); i++ {
+		events = append(events, s.recent[(s.start+i)%len(s.recent)])
+	}
+	return events, complete
+}
+
+// Subscribe adds a registered client to a topic. Subscriptions end when
+// the client is unregistered.
+func (h *Hub) Subscribe(id, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.streams[id]; !ok {
+		return ErrClientNotFound
+	}
+
+	members, ok := h.topics[topic]
+	if !ok {
+		members = make(map[string]struct{})
+		h.topics[topic] = members
+	}
+	members[id] = struct{}{}
+	return nil
+}
+
+func (h *Hub) Unsubscribe(id, topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if members, ok := h.topics[topic]; ok {
+		delete(members, id)
+		if len(members) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+func (h *Hub) unsubscribeAll(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for topic, members := range h.topics {
+		delete(members, id)
+		if len(members) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Publish queues an event for every client subscribed to topic and
+// returns how many clients accepted it.
+func (h *Hub) Publish(topic string, event Event) int {
+	h.mu.RLock()
+	streams := make([]*stream, 0, len(h.topics[topic]))
+	for id := range h.topics[topic] {
+		if st, ok := h.streams[id]; ok {
+			streams = append(streams, st)
+		}
+	}
+	h.mu.RUnlock()
+
+	delivered := 0
+	for _, st := range streams {
+		if h.deliver(st, event, true) == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Dropped returns how many events the hub has discarded for slow clients.
+func (h *Hub) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	streams := make([]*stream, 0, len(h.streams))
+	for _, st := range h.streams {
+		streams = append(streams, st)
+	}
+	h.mu.RUnlock()
+
+	n := 0
+	for _, st := range streams {
+		st.mu.Lock()
+		if st.client != nil {
+			n++
+		}
+		st.mu.Unlock()
+	}
+	return n
+}
+
+// NewClient creates a client with the default options, for streams that
+// are written directly with Send rather than through a hub.
 func NewClient(id string, w http.ResponseWriter) *Client {
+	return newClient(id, w, Options{}.withDefaults())
+}
+
+func newClient(id string, w http.ResponseWriter, opts Options) *Client {
 	return &Client{
 		ID:       id,
-		Events:   make(chan Event, 256),
+		Events:   make(chan Event, opts.BufferSize),
 		Response: w,
+		opts:     opts,
+		done:     make(chan struct{}),
 	}
 }
 
-func (c *Client) Send(event Event) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// enqueue hands an event to the client's writer, applying the slow client
+// policy when the queue is full. It never blocks.
+func (c *Client) enqueue(event Event) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
 
-	if c.closed {
-		return fmt.Errorf("client is closed")
+	if c.opts.Policy == Block {
+		return c.hold(event)
 	}
 
-	fmt.Fprintf(c.Response, "event: %s\n", event.Type)
-	
+	select {
+	case c.Events <- event:
+		return nil
+	default:
+	}
+
+	if c.opts.Policy == DropOldest {
+		for {
+			select {
+			case <-c.Events:
+				c.drop()
+			default:
+			}
+			select {
+			case c.Events <- event:
+				return nil
+			default:
+			}
+		}
+	}
+
+	c.drop()
+	return ErrBufferFull
+}
+
+// hold queues an event under the Block policy. Events go behind any that
+// are already held, so the client sees them in order.
+func (c *Client) hold(event Event) error {
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+
+	if len(c.held) == 0 {
+		select {
+		case c.Events <- event:
+			return nil
+		default:
+		}
+	}
+
+	if len(c.held) >= cap(c.Events) {
+		c.drop()
+		return ErrBufferFull
+	}
+	c.held = append(c.held, event)
+	if !c.waiting {
+		c.waiting = true
+		go c.wait()
+	}
+	return nil
+}
+
+// wait moves held events into the queue as the writer makes room. A client
+// that takes no event within the write timeout is closed.
+func (c *Client) wait() {
+	for {
+		c.heldMu.Lock()
+		if len(c.held) == 0 {
+			c.waiting = false
+			c.heldMu.Unlock()
+			return
+		}
+		event := c.held[0]
+		c.heldMu.Unlock()
+
+		timer := time.NewTimer(c.opts.WriteTimeout)
+		select {
+		case c.Events <- event:
+			timer.Stop()
+			c.heldMu.Lock()
+			c.held = c.held[1:]
+			c.heldMu.Unlock()
+			continue
+		case <-c.done:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		c.heldMu.Lock()
+		for range c.held {
+			c.drop()
+		}
+		c.held = nil
+		c.waiting = false
+		c.heldMu.Unlock()
+		c.Close()
+		return
+	}
+}
+
+func (c *Client) drop() {
+	c.dropped.Add(1)
+	if c.hubDropped != nil {
+		c.hubDropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were discarded for this client.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Serve writes queued events to the response, with a ping every keepAlive,
+// until ctx is done, the client is closed or a write fails. It must run on
+// the goroutine serving the client's HTTP request.
+func (c *Client) Serve(ctx context.Context, keepAlive time.Duration) error {
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return nil
+		case event := <-c.Events:
+			if err := c.Send(event); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := c.Send(Event{Type: "ping"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Send writes an event to the response immediately, bypassing the queue.
+// The write fails if the client cannot take it within the write timeout.
+func (c *Client) Send(event Event) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	var frame bytes.Buffer
+	fmt.Fprintf(&frame, "event: %s\n", event.Type)
+
 	if event.ID != "" {
-		fmt.Fprintf(c.Response, "id: %s\n", event.ID)
+		fmt.Fprintf(&frame, "id: %s\n", event.ID)
 	}
 
-	if event.Data != nil {
-		data, err := json.Marshal(event.Data)
+	switch data := event.Data.(type) {
+	case nil:
+	case string:
+		// Strings go out verbatim, one data line per line of text.
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(&frame, "data: %s\n", line)
+		}
+	default:
+		encoded, err := json.Marshal(data)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(c.Response, "data: %s\n", data)
+		fmt.Fprintf(&frame, "data: %s\n", encoded)
+	}
+
+	frame.WriteString("\n")
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	rc := http.NewResponseController(c.Response)
+	// Not every ResponseWriter supports deadlines; write without one then.
+	rc.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+	defer rc.SetWriteDeadline(time.Time{})
+
+	if _, err := c.Response.Write(frame.Bytes()); err != nil {
+		return err
 	}
 
-	fmt.Fprintf(c.Response, "\n")
-	
-	if flusher, ok := c.Response.(http.Flusher); ok {
-		flusher.Flush()
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
 	}
 
 	return nil
 }
 
+// Close stops the client. Queued events that were not yet written are
+// discarded.
 func (c *Client) Close() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if !c.closed {
-		close(c.Events)
-		c.closed = true
-	}
-}
\ No newline at end of file
+	c.once.Do(func() { close(c.done) })
+}
@@ -0,0 +1,111 @@
+package sse
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlowClientPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		// errs are the results of sending events 1 to 4 to a client that
+		// has room for two. Events for a disconnected client are kept for
+		// replay without error.
+		errs []error
+		// queued are the IDs the client then has queued, and closed
+		// whether it was disconnected.
+		queued  []string
+		closed  bool
+		dropped uint64
+	}{
+		{Disconnect, []error{nil, nil, ErrBufferFull, nil}, []string{"1", "2"}, true, 1},
+		{DropOldest, []error{nil, nil, nil, nil}, []string{"3", "4"}, false, 2},
+		{Block, []error{nil, nil, nil, nil}, []string{"1", "2", "3", "4"}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			hub := NewHubWithOptions(Options{BufferSize: 2, Policy: tt.policy, WriteTimeout: time.Second})
+			client := hub.NewClient("slow", httptest.NewRecorder())
+			hub.Register(client)
+
+			for i, want := range tt.errs {
+				if err := hub.SendTo("slow", Event{Type: "message"}); !errors.Is(err, want) {
+					t.Errorf("send %d: err = %v, want %v", i+1, err, want)
+				}
+			}
+
+			var queued []string
+			for len(queued) < len(tt.queued) {
+				select {
+				case event := <-client.Events:
+					queued = append(queued, event.ID)
+				case <-time.After(time.Second):
+					t.Fatalf("queued %v, want %v", queued, tt.queued)
+				}
+			}
+			if !reflect.DeepEqual(queued, tt.queued) {
+				t.Errorf("queued %v, want %v", queued, tt.queued)
+			}
+
+			select {
+			case <-client.done:
+				if !tt.closed {
+					t.Error("client was disconnected")
+				}
+			default:
+				if tt.closed {
+					t.Error("client was not disconnected")
+				}
+			}
+			if got := client.Dropped(); got != tt.dropped {
+				t.Errorf("dropped %d events, want %d", got, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, policy := range []Policy{Disconnect, DropOldest, Block} {
+		if got, err := ParsePolicy(policy.String()); err != nil || got != policy {
+			t.Errorf("ParsePolicy(%q) = %v, %v", policy, got, err)
+		}
+	}
+	if _, err := ParsePolicy("wait"); err == nil {
+		t.Error("ParsePolicy accepted an unknown policy")
+	}
+}
+
+func TestBlockPolicyDoesNotStallOtherClients(t *testing.T) {
+	hub := NewHubWithOptions(Options{BufferSize: 1, Policy: Block, WriteTimeout: 200 * time.Millisecond})
+	go hub.Run()
+
+	stuck := hub.NewClient("stuck", httptest.NewRecorder())
+	healthy := hub.NewClient("healthy", httptest.NewRecorder())
+	hub.Register(stuck)
+	hub.Register(healthy)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(Event{Type: "message"})
+		select {
+		case <-healthy.Events:
+		case <-time.After(time.Second):
+			t.Fatalf("broadcast %d not delivered to the healthy client", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("broadcasts took %v; the stuck client held them back", elapsed)
+	}
+
+	select {
+	case <-stuck.done:
+	case <-time.After(time.Second):
+		t.Fatal("stuck client was not disconnected after the write timeout")
+	}
+	if stuck.Dropped() == 0 {
+		t.Error("events held for the stuck client were not counted as dropped")
+	}
+}
@@ -0,0 +1,81 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// drain returns the IDs of the events queued for client.
+func drain(client *Client) []string {
+	var ids []string
+	for {
+		select {
+		case event := <-client.Events:
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestResumeReplaysMissedEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        []string
+		complete    bool
+	}{
+		{"fresh connection", "", nil, true},
+		{"up to date", "5", []string{}, true},
+		{"missed some", "3", []string{"4", "5"}, true},
+		{"missed more than retained", "1", []string{"3", "4", "5"}, false},
+		{"missed all retained", "2", []string{"3", "4", "5"}, true},
+		{"unknown future ID", "9", nil, false},
+		{"malformed ID", "abc", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHubWithOptions(Options{ReplaySize: 3})
+			first := hub.NewClient("session", httptest.NewRecorder())
+			hub.Register(first)
+			hub.Unregister(first)
+			for i := 0; i < 5; i++ {
+				if err := hub.SendTo("session", Event{Type: "message"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			client := hub.NewClient("session", httptest.NewRecorder())
+			complete := hub.Resume(client, tt.lastEventID)
+			if complete != tt.complete {
+				t.Errorf("Resume reported complete %v, want %v", complete, tt.complete)
+			}
+			if got := drain(client); len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+
+			// Events sent after resuming continue the same numbering.
+			hub.SendTo("session", Event{Type: "message"})
+			if got := drain(client); !reflect.DeepEqual(got, []string{"6"}) {
+				t.Errorf("after resuming got %v, want [6]", got)
+			}
+		})
+	}
+}
+
+func TestResumeReplaysNoMoreThanTheBuffer(t *testing.T) {
+	hub := NewHubWithOptions(Options{BufferSize: 2, ReplaySize: 10})
+	hub.Register(hub.NewClient("session", httptest.NewRecorder()))
+	for i := 0; i < 4; i++ {
+		hub.SendTo("session", Event{Type: "message"})
+	}
+
+	client := hub.NewClient("session", httptest.NewRecorder())
+	if hub.Resume(client, "0") {
+		t.Error("Resume reported a complete replay that did not fit the buffer")
+	}
+	if got := drain(client); !reflect.DeepEqual(got, []string{"3", "4"}) {
+		t.Errorf("replayed %v, want the newest events [3 4]", got)
+	}
+}